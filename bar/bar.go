@@ -0,0 +1,296 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package bar builds status bars out of modules. A Bar is a
+// katnip.PanelHandler and is registered like any other panel:
+//
+//	katnip.Register("bar", &bar.Bar{
+//		Left:  []bar.Module{workspaces},
+//		Right: []bar.Module{clock},
+//	})
+package bar
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+
+	"git.sr.ht/~rockorager/vaxis"
+	"github.com/nekorg/katnip"
+)
+
+type Segment = vaxis.Segment
+
+// Module is a piece of the bar. Render is called from a goroutine owned by
+// the bar, once at start and then every Interval. An Interval of 0 renders
+// only at start, on clicks and on Bar.Refresh.
+type Module interface {
+	Render() []Segment
+	Interval() time.Duration
+}
+
+type Click struct {
+	Button vaxis.MouseButton
+	// column relative to the first cell of the module
+	Col int
+}
+
+// Clickable is implemented by modules that react to mouse clicks. Click
+// runs on its own goroutine and the module is re-rendered afterwards.
+type Clickable interface {
+	Click(c Click)
+}
+
+type funcModule struct {
+	interval time.Duration
+	render   func() []Segment
+}
+
+func (m *funcModule) Render() []Segment       { return m.render() }
+func (m *funcModule) Interval() time.Duration { return m.interval }
+
+// Func returns a Module rendering fn every interval.
+func Func(interval time.Duration, fn func() []Segment) Module {
+	return &funcModule{interval: interval, render: fn}
+}
+
+type slot struct {
+	module   Module
+	refresh  chan struct{}
+	segments []Segment
+
+	// position of the last draw, used for clicks
+	col, width int
+}
+
+type Bar struct {
+	Left, Center, Right []Module
+
+	// drawn between modules of the same group
+	Separator []Segment
+
+	// fills the bar, also used for segments without a background
+	Background vaxis.Style
+
+	Options vaxis.Options
+
+	mu    sync.Mutex
+	slots []*slot
+	row   int
+}
+
+// Refresh re-renders m as soon as possible.
+func (b *Bar) Refresh(m Module) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, s := range b.slots {
+		if sameModule(s.module, m) {
+			select {
+			case s.refresh <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+func (b *Bar) Run(k *katnip.Kitty, rw io.ReadWriter) int {
+	return b.RunInstance(&katnip.Instance{Kitty: k, RW: rw})
+}
+
+func (b *Bar) RunInstance(in *katnip.Instance) int {
+	return b.RunContext(context.Background(), in)
+}
+
+func (b *Bar) RunContext(ctx context.Context, in *katnip.Instance) int {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	b.mu.Lock()
+	b.slots = nil
+	for _, group := range [][]Module{b.Left, b.Center, b.Right} {
+		for _, m := range group {
+			b.slots = append(b.slots, &slot{module: m, refresh: make(chan struct{}, 1)})
+		}
+	}
+	b.mu.Unlock()
+
+	panel := &katnip.TUIPanel{
+		Options: b.Options,
+		Init: func(t *katnip.TUI) error {
+			for _, s := range b.slots {
+				go b.schedule(ctx, t, s)
+			}
+			return nil
+		},
+		Render: b.render,
+		Event:  b.event,
+	}
+	return panel.RunContext(ctx, in)
+}
+
+func (b *Bar) schedule(ctx context.Context, t *katnip.TUI, s *slot) {
+	var tick <-chan time.Time
+	if interval := s.module.Interval(); interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		segments := s.module.Render()
+		t.Update(func() { s.segments = segments })
+
+		select {
+		case <-tick:
+		case <-s.refresh:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// groups splits the slots back into left, center and right.
+func (b *Bar) groups() [3][]*slot {
+	var g [3][]*slot
+	n := 0
+	for i, size := range []int{len(b.Left), len(b.Center), len(b.Right)} {
+		g[i] = b.slots[n : n+size]
+		n += size
+	}
+	return g
+}
+
+func (b *Bar) render(t *katnip.TUI, win vaxis.Window) {
+	win.Fill(vaxis.Cell{
+		Character: vaxis.Character{Grapheme: " ", Width: 1},
+		Style:     b.Background,
+	})
+
+	cols, rows := win.Size()
+	b.row = rows / 2
+	line := win.New(0, b.row, cols, 1)
+
+	g := b.groups()
+	leftWidth := b.width(t, g[0])
+	centerWidth := b.width(t, g[1])
+	rightWidth := b.width(t, g[2])
+
+	b.draw(t, line, g[0], 0)
+	b.draw(t, line, g[1], max((cols-centerWidth)/2, leftWidth))
+	b.draw(t, line, g[2], max(cols-rightWidth, 0))
+}
+
+func (b *Bar) width(t *katnip.TUI, slots []*slot) int {
+	w := 0
+	visible := 0
+	for _, s := range slots {
+		sw := segmentsWidth(t, s.segments)
+		if sw > 0 {
+			w += sw
+			visible++
+		}
+	}
+	if visible > 1 {
+		w += (visible - 1) * segmentsWidth(t, b.Separator)
+	}
+	return w
+}
+
+func (b *Bar) draw(t *katnip.TUI, line vaxis.Window, slots []*slot, col int) {
+	cols, _ := line.Size()
+	first := true
+	for _, s := range slots {
+		s.col, s.width = 0, 0
+
+		w := segmentsWidth(t, s.segments)
+		if w == 0 || col >= cols {
+			continue
+		}
+		if !first {
+			sep := segmentsWidth(t, b.Separator)
+			line.New(col, 0, sep, 1).Print(b.withBackground(b.Separator)...)
+			col += sep
+		}
+		first = false
+
+		s.col, s.width = col, w
+		line.New(col, 0, w, 1).Print(b.withBackground(s.segments)...)
+		col += w
+	}
+}
+
+func (b *Bar) withBackground(segments []Segment) []Segment {
+	out := make([]Segment, len(segments))
+	for i, seg := range segments {
+		if seg.Style.Background == 0 {
+			seg.Style.Background = b.Background.Background
+		}
+		if seg.Style.Foreground == 0 {
+			seg.Style.Foreground = b.Background.Foreground
+		}
+		out[i] = seg
+	}
+	return out
+}
+
+func (b *Bar) event(t *katnip.TUI, ev vaxis.Event) {
+	mouse, ok := ev.(vaxis.Mouse)
+	if !ok || mouse.EventType != vaxis.EventPress || mouse.Row != b.row {
+		return
+	}
+
+	for _, s := range b.slots {
+		if s.width == 0 || mouse.Col < s.col || mouse.Col >= s.col+s.width {
+			continue
+		}
+		c, ok := s.module.(Clickable)
+		if !ok {
+			return
+		}
+		click := Click{Button: mouse.Button, Col: mouse.Col - s.col}
+		go func() {
+			c.Click(click)
+			select {
+			case s.refresh <- struct{}{}:
+			default:
+			}
+		}()
+		return
+	}
+}
+
+func segmentsWidth(t *katnip.TUI, segments []Segment) int {
+	w := 0
+	for _, seg := range segments {
+		w += t.Vaxis.RenderedWidth(seg.Text)
+	}
+	return w
+}
+
+// sameModule compares modules without panicking on uncomparable types.
+func sameModule(a, b Module) bool {
+	ta := reflect.TypeOf(a)
+	if ta == nil || ta != reflect.TypeOf(b) || !ta.Comparable() {
+		return false
+	}
+	return a == b
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package modules
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"git.sr.ht/~rockorager/vaxis"
+	"github.com/nekorg/katnip/bar"
+)
+
+type BatteryInfo struct {
+	Name string
+	// 0-100
+	Capacity int
+	// as reported by the kernel: Charging, Discharging, Full, Not charging, Unknown
+	Status string
+}
+
+// ReadBatteries returns every power supply of type Battery.
+func ReadBatteries(root string) ([]BatteryInfo, error) {
+	dir := rootPath(root, "sys", "class", "power_supply")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var batteries []BatteryInfo
+	for _, e := range entries {
+		supply := rootPath(dir, e.Name())
+		if typ, err := readTrimmed(rootPath(supply, "type")); err != nil || typ != "Battery" {
+			continue
+		}
+
+		capacity, err := readBatteryCapacity(supply)
+		if err != nil {
+			continue
+		}
+		status, _ := readTrimmed(rootPath(supply, "status"))
+
+		batteries = append(batteries, BatteryInfo{
+			Name:     e.Name(),
+			Capacity: capacity,
+			Status:   status,
+		})
+	}
+	return batteries, nil
+}
+
+func readBatteryCapacity(supply string) (int, error) {
+	if c, err := readInt(rootPath(supply, "capacity")); err == nil {
+		return int(c), nil
+	}
+
+	// drivers without capacity report either energy (µWh) or charge (µAh)
+	for _, kind := range []string{"energy", "charge"} {
+		now, err := readInt(rootPath(supply, kind+"_now"))
+		if err != nil {
+			continue
+		}
+		full, err := readInt(rootPath(supply, kind+"_full"))
+		if err != nil || full == 0 {
+			continue
+		}
+		return int(100 * now / full), nil
+	}
+	return 0, fmt.Errorf("%s: no capacity information", supply)
+}
+
+type Battery struct {
+	Root string
+	// battery to show, e.g. BAT0. default: the first one found
+	Name string
+	// default: 30s
+	Every time.Duration
+	// default: "BAT 87%", with a + while charging
+	Format func(b BatteryInfo) string
+	Style  vaxis.Style
+}
+
+func (b *Battery) Interval() time.Duration {
+	return interval(b.Every, 30*time.Second)
+}
+
+func (b *Battery) Render() []bar.Segment {
+	batteries, err := ReadBatteries(b.Root)
+	if err != nil {
+		return nil
+	}
+
+	for _, info := range batteries {
+		if b.Name != "" && info.Name != b.Name {
+			continue
+		}
+		if b.Format != nil {
+			return text(b.Style, b.Format(info))
+		}
+		sign := ""
+		if info.Status == "Charging" {
+			sign = "+"
+		}
+		return text(b.Style, fmt.Sprintf("BAT %d%%%s", info.Capacity, sign))
+	}
+	return nil
+}
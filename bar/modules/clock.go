@@ -0,0 +1,53 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package modules
+
+import (
+	"time"
+
+	"git.sr.ht/~rockorager/vaxis"
+	"github.com/nekorg/katnip/bar"
+)
+
+type Clock struct {
+	// time layout, default: "15:04"
+	Format string
+	// default: time.Local
+	Location *time.Location
+	// default: 1s
+	Every time.Duration
+	Style vaxis.Style
+}
+
+func (c *Clock) Interval() time.Duration {
+	return interval(c.Every, time.Second)
+}
+
+func (c *Clock) Render() []bar.Segment {
+	format := c.Format
+	if format == "" {
+		format = "15:04"
+	}
+	now := time.Now()
+	if c.Location != nil {
+		now = now.In(c.Location)
+	}
+	return text(c.Style, now.Format(format))
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package modules
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.sr.ht/~rockorager/vaxis"
+	"github.com/nekorg/katnip/bar"
+)
+
+// CPUTimes is the aggregate "cpu" line of /proc/stat, in clock ticks.
+type CPUTimes struct {
+	Idle  uint64
+	Total uint64
+}
+
+func ReadCPUTimes(root string) (CPUTimes, error) {
+	path := rootPath(root, "proc", "stat")
+	f, err := os.Open(path)
+	if err != nil {
+		return CPUTimes{}, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || fields[0] != "cpu" {
+			continue
+		}
+
+		var t CPUTimes
+		// user nice system idle iowait irq softirq steal, guest time is
+		// already accounted in user and nice
+		for i, field := range fields[1:min(len(fields), 9)] {
+			v, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return CPUTimes{}, fmt.Errorf("parsing %s: %w", path, err)
+			}
+			t.Total += v
+			if i == 3 || i == 4 {
+				t.Idle += v
+			}
+		}
+		return t, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return CPUTimes{}, err
+	}
+	return CPUTimes{}, fmt.Errorf("%s: no cpu line", path)
+}
+
+// Usage returns the busy percentage between prev and t.
+func (t CPUTimes) Usage(prev CPUTimes) float64 {
+	total := t.Total - prev.Total
+	if total == 0 || t.Total < prev.Total {
+		return 0
+	}
+	return 100 * float64(total-(t.Idle-prev.Idle)) / float64(total)
+}
+
+type CPU struct {
+	Root string
+	// default: 2s
+	Every time.Duration
+	// default: "CPU 12%"
+	Format func(percent float64) string
+	Style  vaxis.Style
+
+	prev    CPUTimes
+	sampled bool
+}
+
+func (c *CPU) Interval() time.Duration {
+	return interval(c.Every, 2*time.Second)
+}
+
+func (c *CPU) Render() []bar.Segment {
+	t, err := ReadCPUTimes(c.Root)
+	if err != nil {
+		return nil
+	}
+
+	var usage float64
+	if c.sampled {
+		usage = t.Usage(c.prev)
+	}
+	c.prev, c.sampled = t, true
+
+	if c.Format != nil {
+		return text(c.Style, c.Format(usage))
+	}
+	return text(c.Style, fmt.Sprintf("CPU %.0f%%", usage))
+}
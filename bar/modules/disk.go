@@ -0,0 +1,92 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package modules
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+
+	"git.sr.ht/~rockorager/vaxis"
+	"github.com/nekorg/katnip/bar"
+)
+
+// DiskUsage of a filesystem, in bytes.
+type DiskUsage struct {
+	Total uint64
+	Free  uint64
+	// free space available to unprivileged users
+	Available uint64
+}
+
+func (d DiskUsage) Used() uint64 {
+	return d.Total - d.Free
+}
+
+func (d DiskUsage) UsedPercent() float64 {
+	// same as df: used / (used + available)
+	denom := d.Used() + d.Available
+	if denom == 0 {
+		return 0
+	}
+	return 100 * float64(d.Used()) / float64(denom)
+}
+
+func ReadDiskUsage(path string) (DiskUsage, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return DiskUsage{}, fmt.Errorf("statfs %s: %w", path, err)
+	}
+	bsize := uint64(st.Bsize)
+	return DiskUsage{
+		Total:     st.Blocks * bsize,
+		Free:      st.Bfree * bsize,
+		Available: st.Bavail * bsize,
+	}, nil
+}
+
+type Disk struct {
+	// any path on the filesystem, default: "/"
+	Path string
+	// default: 30s
+	Every time.Duration
+	// default: "/ 120G free"
+	Format func(d DiskUsage) string
+	Style  vaxis.Style
+}
+
+func (d *Disk) Interval() time.Duration {
+	return interval(d.Every, 30*time.Second)
+}
+
+func (d *Disk) Render() []bar.Segment {
+	path := d.Path
+	if path == "" {
+		path = "/"
+	}
+	usage, err := ReadDiskUsage(path)
+	if err != nil {
+		return nil
+	}
+	if d.Format != nil {
+		return text(d.Style, d.Format(usage))
+	}
+	return text(d.Style, fmt.Sprintf("%s %s free", path, HumanBytes(float64(usage.Available))))
+}
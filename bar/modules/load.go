@@ -0,0 +1,79 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package modules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.sr.ht/~rockorager/vaxis"
+	"github.com/nekorg/katnip/bar"
+)
+
+// LoadAvg holds the 1, 5 and 15 minute load averages.
+type LoadAvg [3]float64
+
+func ReadLoadAvg(root string) (LoadAvg, error) {
+	path := rootPath(root, "proc", "loadavg")
+	s, err := readTrimmed(path)
+	if err != nil {
+		return LoadAvg{}, err
+	}
+
+	fields := strings.Fields(s)
+	if len(fields) < 3 {
+		return LoadAvg{}, fmt.Errorf("%s: unexpected format", path)
+	}
+
+	var l LoadAvg
+	for i := range l {
+		l[i], err = strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return LoadAvg{}, fmt.Errorf("parsing %s: %w", path, err)
+		}
+	}
+	return l, nil
+}
+
+type Load struct {
+	Root string
+	// default: 5s
+	Every time.Duration
+	// default: "0.52 0.58 0.59"
+	Format func(l LoadAvg) string
+	Style  vaxis.Style
+}
+
+func (l *Load) Interval() time.Duration {
+	return interval(l.Every, 5*time.Second)
+}
+
+func (l *Load) Render() []bar.Segment {
+	avg, err := ReadLoadAvg(l.Root)
+	if err != nil {
+		return nil
+	}
+	if l.Format != nil {
+		return text(l.Style, l.Format(avg))
+	}
+	return text(l.Style, fmt.Sprintf("%.2f %.2f %.2f", avg[0], avg[1], avg[2]))
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package modules
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.sr.ht/~rockorager/vaxis"
+	"github.com/nekorg/katnip/bar"
+)
+
+// MemInfo holds values from /proc/meminfo, in bytes.
+type MemInfo struct {
+	Total     uint64
+	Available uint64
+	SwapTotal uint64
+	SwapFree  uint64
+}
+
+func (m MemInfo) Used() uint64 {
+	return m.Total - m.Available
+}
+
+func (m MemInfo) UsedPercent() float64 {
+	if m.Total == 0 {
+		return 0
+	}
+	return 100 * float64(m.Used()) / float64(m.Total)
+}
+
+func ReadMemInfo(root string) (MemInfo, error) {
+	path := rootPath(root, "proc", "meminfo")
+	f, err := os.Open(path)
+	if err != nil {
+		return MemInfo{}, err
+	}
+	defer f.Close()
+
+	var m MemInfo
+	fields := map[string]*uint64{
+		"MemTotal":     &m.Total,
+		"MemAvailable": &m.Available,
+		"SwapTotal":    &m.SwapTotal,
+		"SwapFree":     &m.SwapFree,
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, rest, ok := strings.Cut(scanner.Text(), ":")
+		dst := fields[key]
+		if !ok || dst == nil {
+			continue
+		}
+		value := strings.TrimSuffix(strings.TrimSpace(rest), " kB")
+		v, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return MemInfo{}, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		*dst = v * 1024
+	}
+	if err := scanner.Err(); err != nil {
+		return MemInfo{}, err
+	}
+	if m.Total == 0 {
+		return MemInfo{}, fmt.Errorf("%s: MemTotal missing", path)
+	}
+	return m, nil
+}
+
+type Memory struct {
+	Root string
+	// default: 5s
+	Every time.Duration
+	// default: "MEM 42%"
+	Format func(m MemInfo) string
+	Style  vaxis.Style
+}
+
+func (m *Memory) Interval() time.Duration {
+	return interval(m.Every, 5*time.Second)
+}
+
+func (m *Memory) Render() []bar.Segment {
+	info, err := ReadMemInfo(m.Root)
+	if err != nil {
+		return nil
+	}
+	if m.Format != nil {
+		return text(m.Style, m.Format(info))
+	}
+	return text(m.Style, fmt.Sprintf("MEM %.0f%%", info.UsedPercent()))
+}
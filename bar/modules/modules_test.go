@@ -0,0 +1,224 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package modules
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nekorg/katnip/bar"
+)
+
+// fixture trees mirroring /proc and /sys
+const (
+	laptop  = "testdata/laptop"
+	broken  = "testdata/broken"
+	missing = "testdata/missing"
+)
+
+func TestReadBatteries(t *testing.T) {
+	got, err := ReadBatteries(laptop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// AC is not a battery and BAT3 reports no capacity
+	want := []BatteryInfo{
+		{Name: "BAT0", Capacity: 87, Status: "Charging"},
+		{Name: "BAT1", Capacity: 25, Status: "Discharging"},
+		{Name: "BAT2", Capacity: 75, Status: "Full"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadBatteries() = %+v, want %+v", got, want)
+	}
+
+	if _, err := ReadBatteries(missing); err == nil {
+		t.Error("ReadBatteries(missing) succeeded")
+	}
+}
+
+func TestReadThermalZones(t *testing.T) {
+	got, err := ReadThermalZones(laptop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// cooling devices and zones without a readable temp are skipped
+	want := []ThermalZone{
+		{Name: "thermal_zone0", Type: "acpitz", Temp: 45},
+		{Name: "thermal_zone1", Type: "x86_pkg_temp", Temp: 52.5},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadThermalZones() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReadCPUTimes(t *testing.T) {
+	tests := []struct {
+		root    string
+		want    CPUTimes
+		wantErr bool
+	}{
+		// guest and guest_nice are already part of user and nice
+		{root: laptop, want: CPUTimes{Idle: 750, Total: 1000}},
+		{root: broken, wantErr: true},
+		{root: missing, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ReadCPUTimes(tt.root)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ReadCPUTimes(%s) = %+v, %v, want %+v, error %t", tt.root, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCPUTimesUsage(t *testing.T) {
+	tests := []struct {
+		prev, cur CPUTimes
+		want      float64
+	}{
+		{CPUTimes{Idle: 700, Total: 900}, CPUTimes{Idle: 750, Total: 1000}, 50},
+		{CPUTimes{Idle: 750, Total: 1000}, CPUTimes{Idle: 750, Total: 1000}, 0},
+		// counters reset, e.g. after resume
+		{CPUTimes{Idle: 750, Total: 1000}, CPUTimes{Idle: 10, Total: 20}, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.cur.Usage(tt.prev); got != tt.want {
+			t.Errorf("%+v.Usage(%+v) = %v, want %v", tt.cur, tt.prev, got, tt.want)
+		}
+	}
+}
+
+func TestReadLoadAvg(t *testing.T) {
+	tests := []struct {
+		root    string
+		want    LoadAvg
+		wantErr bool
+	}{
+		{root: laptop, want: LoadAvg{0.52, 0.58, 0.59}},
+		{root: broken, wantErr: true},
+		{root: missing, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ReadLoadAvg(tt.root)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ReadLoadAvg(%s) = %v, %v, want %v, error %t", tt.root, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestReadMemInfo(t *testing.T) {
+	tests := []struct {
+		root    string
+		want    MemInfo
+		wantErr bool
+	}{
+		{root: laptop, want: MemInfo{
+			Total:     16000000 * 1024,
+			Available: 4000000 * 1024,
+			SwapTotal: 8000000 * 1024,
+			SwapFree:  6000000 * 1024,
+		}},
+		// no MemTotal
+		{root: broken, wantErr: true},
+		{root: missing, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ReadMemInfo(tt.root)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ReadMemInfo(%s) = %+v, %v, want %+v, error %t", tt.root, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestReadNetDev(t *testing.T) {
+	tests := []struct {
+		root    string
+		want    map[string]NetCounters
+		wantErr bool
+	}{
+		{root: laptop, want: map[string]NetCounters{
+			"lo":    {RxBytes: 5000, TxBytes: 5000},
+			"eth0":  {RxBytes: 1048576, TxBytes: 524288},
+			"wlan0": {RxBytes: 2048, TxBytes: 1024},
+		}},
+		{root: broken, wantErr: true},
+		{root: missing, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ReadNetDev(tt.root)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ReadNetDev(%s) = %+v, %v, want %+v, error %t", tt.root, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name   string
+		module bar.Module
+		want   string
+	}{
+		{"battery", &Battery{Root: laptop}, "BAT 87%+"},
+		{"battery by name", &Battery{Root: laptop, Name: "BAT1"}, "BAT 25%"},
+		{"battery not found", &Battery{Root: laptop, Name: "BAT9"}, ""},
+		{"battery missing", &Battery{Root: missing}, ""},
+		{"temperature", &Temperature{Root: laptop}, "45°C"},
+		{"temperature by type", &Temperature{Root: laptop, Zone: "x86_pkg_temp"}, "52°C"},
+		{"temperature by name", &Temperature{Root: laptop, Zone: "thermal_zone0"}, "45°C"},
+		{"load", &Load{Root: laptop}, "0.52 0.58 0.59"},
+		{"load broken", &Load{Root: broken}, ""},
+		{"memory", &Memory{Root: laptop}, "MEM 75%"},
+		{"memory broken", &Memory{Root: broken}, ""},
+		// the first sample has nothing to compare against
+		{"cpu", &CPU{Root: laptop}, "CPU 0%"},
+		{"network", &Network{Root: laptop}, "↓0B ↑0B"},
+		{"network missing", &Network{Root: missing}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			for _, s := range tt.module.Render() {
+				got += s.Text
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHumanBytes(t *testing.T) {
+	tests := []struct {
+		n    float64
+		want string
+	}{
+		{0, "0B"},
+		{1023, "1023B"},
+		{1024, "1.0K"},
+		{1536, "1.5K"},
+		{20 * 1024, "20K"},
+		{1048576, "1.0M"},
+		{3 << 30, "3.0G"},
+	}
+	for _, tt := range tests {
+		if got := HumanBytes(tt.n); got != tt.want {
+			t.Errorf("HumanBytes(%v) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
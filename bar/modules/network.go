@@ -0,0 +1,126 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package modules
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.sr.ht/~rockorager/vaxis"
+	"github.com/nekorg/katnip/bar"
+)
+
+// NetCounters are the cumulative byte counters of an interface.
+type NetCounters struct {
+	RxBytes uint64
+	TxBytes uint64
+}
+
+// ReadNetDev returns the counters of every interface in /proc/net/dev.
+func ReadNetDev(root string) (map[string]NetCounters, error) {
+	path := rootPath(root, "proc", "net", "dev")
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	counters := map[string]NetCounters{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		name, rest, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			// header lines
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) < 9 {
+			return nil, fmt.Errorf("%s: unexpected format", path)
+		}
+		rx, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		tx, err := strconv.ParseUint(fields[8], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		counters[strings.TrimSpace(name)] = NetCounters{RxBytes: rx, TxBytes: tx}
+	}
+	return counters, scanner.Err()
+}
+
+// Throughput in bytes per second.
+type Throughput struct {
+	Rx, Tx float64
+}
+
+type Network struct {
+	Root string
+	// interface to show. default: the sum of all except lo
+	Interface string
+	// default: 2s
+	Every time.Duration
+	// default: "↓1.2M ↑34K"
+	Format func(t Throughput) string
+	Style  vaxis.Style
+
+	prev     NetCounters
+	prevTime time.Time
+}
+
+func (n *Network) Interval() time.Duration {
+	return interval(n.Every, 2*time.Second)
+}
+
+func (n *Network) Render() []bar.Segment {
+	counters, err := ReadNetDev(n.Root)
+	if err != nil {
+		return nil
+	}
+
+	var cur NetCounters
+	for name, c := range counters {
+		if (n.Interface == "" && name != "lo") || name == n.Interface {
+			cur.RxBytes += c.RxBytes
+			cur.TxBytes += c.TxBytes
+		}
+	}
+
+	now := time.Now()
+	var t Throughput
+	if !n.prevTime.IsZero() && cur.RxBytes >= n.prev.RxBytes && cur.TxBytes >= n.prev.TxBytes {
+		secs := now.Sub(n.prevTime).Seconds()
+		if secs > 0 {
+			t.Rx = float64(cur.RxBytes-n.prev.RxBytes) / secs
+			t.Tx = float64(cur.TxBytes-n.prev.TxBytes) / secs
+		}
+	}
+	n.prev, n.prevTime = cur, now
+
+	if n.Format != nil {
+		return text(n.Style, n.Format(t))
+	}
+	return text(n.Style, fmt.Sprintf("↓%s ↑%s", HumanBytes(t.Rx), HumanBytes(t.Tx)))
+}
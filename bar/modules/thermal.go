@@ -0,0 +1,100 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package modules
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"git.sr.ht/~rockorager/vaxis"
+	"github.com/nekorg/katnip/bar"
+)
+
+type ThermalZone struct {
+	// e.g. thermal_zone0
+	Name string
+	// e.g. x86_pkg_temp, acpitz
+	Type string
+	// degrees Celsius
+	Temp float64
+}
+
+func ReadThermalZones(root string) ([]ThermalZone, error) {
+	dir := rootPath(root, "sys", "class", "thermal")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var zones []ThermalZone
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), "thermal_zone") {
+			continue
+		}
+		zone := rootPath(dir, e.Name())
+		milli, err := readInt(rootPath(zone, "temp"))
+		if err != nil {
+			continue
+		}
+		typ, _ := readTrimmed(rootPath(zone, "type"))
+		zones = append(zones, ThermalZone{
+			Name: e.Name(),
+			Type: typ,
+			Temp: float64(milli) / 1000,
+		})
+	}
+	return zones, nil
+}
+
+type Temperature struct {
+	Root string
+	// zone to show, matched against both name and type.
+	// default: the first zone found
+	Zone string
+	// default: 30s
+	Every time.Duration
+	// default: "52°C"
+	Format func(z ThermalZone) string
+	Style  vaxis.Style
+}
+
+func (t *Temperature) Interval() time.Duration {
+	return interval(t.Every, 30*time.Second)
+}
+
+func (t *Temperature) Render() []bar.Segment {
+	zones, err := ReadThermalZones(t.Root)
+	if err != nil {
+		return nil
+	}
+
+	for _, z := range zones {
+		if t.Zone != "" && z.Name != t.Zone && z.Type != t.Zone {
+			continue
+		}
+		if t.Format != nil {
+			return text(t.Style, t.Format(z))
+		}
+		return text(t.Style, fmt.Sprintf("%.0f°C", z.Temp))
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// DispatchAsync sends a command with kitty's no_response flag and returns
+// once it is written, without waiting for kitty to run it. Failures on
+// kitty's side go unnoticed.
+func (k *Kitty) DispatchAsync(cmd string, payload any) error {
+	return k.DispatchAsyncContext(context.Background(), cmd, payload)
+}
+
+// DispatchAsyncContext is DispatchAsync bounded by ctx.
+func (k *Kitty) DispatchAsyncContext(ctx context.Context, cmd string, payload any) error {
+	_, err := k.exchange(ctx, cmd, isIdempotent(cmd, payload), func() ([]byte, error) {
+		msg, err := k.encodeCommand(cmd, payload, true)
+		if err != nil {
+			return nil, err
+		}
+		return nil, k.write(msg)
+	})
+	return err
+}
+
+// DoAsync is DispatchAsync for a typed request.
+func (k *Kitty) DoAsync(req Request) error {
+	return k.DispatchAsyncContext(context.Background(), req.Cmd(), req)
+}
+
+// Batch collects commands to send back-to-back in a single write, for
+// callers that issue many commands in a row, e.g. to slide a panel with
+// repeated moves. A Batch is not safe for concurrent use.
+type Batch struct {
+	k    *Kitty
+	cmds []batchCmd
+}
+
+type batchCmd struct {
+	name    string
+	payload any
+}
+
+// Batch returns an empty batch of commands for k.
+func (k *Kitty) Batch() *Batch {
+	return &Batch{k: k}
+}
+
+// Add appends a typed request.
+func (b *Batch) Add(req Request) *Batch {
+	return b.AddCommand(req.Cmd(), req)
+}
+
+// AddCommand appends a command with an untyped payload.
+func (b *Batch) AddCommand(cmd string, payload any) *Batch {
+	b.cmds = append(b.cmds, batchCmd{name: cmd, payload: payload})
+	return b
+}
+
+func (b *Batch) Move(x, y int) *Batch {
+	return b.Add(moveRequest(x, y))
+}
+
+func (b *Batch) Resize(columns, lines int) *Batch {
+	return b.Add(resizeRequest(columns, lines))
+}
+
+func (b *Batch) SetMargins(m Margins) *Batch {
+	return b.Add(marginsRequest(m))
+}
+
+func (b *Batch) SetOpacity(opacity float64) *Batch {
+	return b.Add(SetBackgroundOpacityRequest{Opacity: opacity})
+}
+
+// Len returns the number of queued commands.
+func (b *Batch) Len() int {
+	return len(b.cmds)
+}
+
+// Send writes all queued commands with no_response set and returns without
+// waiting for kitty, like DispatchAsync. The batch is emptied.
+func (b *Batch) Send(ctx context.Context) error {
+	if len(b.cmds) == 0 {
+		return nil
+	}
+	cmds, idempotent := b.take()
+
+	_, err := b.k.exchange(ctx, "batch", idempotent, func() ([]byte, error) {
+		msg, err := b.k.encodeBatch(cmds, true)
+		if err != nil {
+			return nil, err
+		}
+		return nil, b.k.write(msg)
+	})
+	return err
+}
+
+// Exec writes all queued commands at once, then reads their responses in
+// order. Every command runs even if an earlier one fails; the returned
+// error joins the failures. The batch is emptied.
+func (b *Batch) Exec(ctx context.Context) error {
+	if len(b.cmds) == 0 {
+		return nil
+	}
+	cmds, idempotent := b.take()
+
+	_, err := b.k.exchange(ctx, "batch", idempotent, func() ([]byte, error) {
+		msg, err := b.k.encodeBatch(cmds, false)
+		if err != nil {
+			return nil, err
+		}
+		if err := b.k.write(msg); err != nil {
+			return nil, err
+		}
+
+		var errs []error
+		for i, cmd := range cmds {
+			if _, err := b.k.readResponse(); err != nil {
+				var ce *connError
+				if errors.As(err, &ce) {
+					return nil, err
+				}
+				errs = append(errs, fmt.Errorf("%s (#%d): %w", cmd.name, i, err))
+			}
+		}
+		return nil, errors.Join(errs...)
+	})
+	return err
+}
+
+// take empties the batch and reports whether all its commands are
+// idempotent.
+func (b *Batch) take() ([]batchCmd, bool) {
+	cmds := b.cmds
+	b.cmds = nil
+
+	idempotent := true
+	for _, cmd := range cmds {
+		idempotent = idempotent && isIdempotent(cmd.name, cmd.payload)
+	}
+	return cmds, idempotent
+}
+
+// encodeBatch frames cmds back-to-back. Must be called with k.mu held.
+func (k *Kitty) encodeBatch(cmds []batchCmd, noResponse bool) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, cmd := range cmds {
+		msg, err := k.encodeCommand(cmd.name, cmd.payload, noResponse)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(msg)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nekorg/katnip"
+	"github.com/nekorg/katnip/katniptest"
+)
+
+func TestBatchExec(t *testing.T) {
+	k, s := newKitty(t)
+	s.Respond("set-window-title", katniptest.OK(nil), katniptest.Error("no such window"))
+
+	err := k.Batch().
+		Add(katnip.SetWindowTitleRequest{Title: "a"}).
+		Add(katnip.SetWindowTitleRequest{Title: "b"}).
+		SetOpacity(0.5).
+		Exec(t.Context())
+	if err == nil || !strings.Contains(err.Error(), "set-window-title (#1): kitty error: no such window") {
+		t.Errorf("error = %v", err)
+	}
+
+	cmds := s.Commands()
+	if len(cmds) != 3 {
+		t.Fatalf("got %d commands, want 3", len(cmds))
+	}
+	for i, want := range []string{"set-window-title", "set-window-title", "set-background-opacity"} {
+		if cmds[i].Name != want || cmds[i].NoResponse {
+			t.Errorf("command %d = %s, no_response %v", i, cmds[i].Name, cmds[i].NoResponse)
+		}
+	}
+
+	// the connection is still in step after a failed command
+	if _, err := k.Ls(katnip.LsRequest{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBatchSend(t *testing.T) {
+	k, s := newKitty(t)
+
+	b := k.Batch()
+	for x := range 5 {
+		b.Move(x, 0)
+	}
+	if err := b.Send(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != 0 {
+		t.Errorf("Len() = %d after Send", b.Len())
+	}
+
+	cmds, err := s.WaitFor(5, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, cmd := range cmds {
+		if !cmd.NoResponse {
+			t.Errorf("command %d sent without no_response", i)
+		}
+	}
+}
+
+func TestBatchNoRetryAfterSend(t *testing.T) {
+	k, s := newKitty(t)
+	s.Respond("ls", katniptest.Disconnect())
+
+	err := k.Batch().
+		Add(katnip.LsRequest{}).
+		Add(katnip.SendTextRequest{Text: "x"}).
+		Exec(t.Context())
+	if err == nil {
+		t.Fatal("no error for a dropped batch")
+	}
+	if _, err := k.Ls(katnip.LsRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(s.Commands(), "send-text"); n > 1 {
+		t.Errorf("send-text sent %d times, want at most 1", n)
+	}
+}
@@ -0,0 +1,302 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// MaxMessageSize is the largest frame a Channel accepts. Larger length
+// prefixes are treated as stream corruption.
+const MaxMessageSize = 16 << 20
+
+var ErrMessageTooLarge = errors.New("message too large")
+
+// Codec encodes message bodies. JSONCodec is used when none is given.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+var JSONCodec Codec = jsonCodec{}
+
+// Message is a single frame received from a Channel.
+type Message struct {
+	Type string
+	Body []byte
+
+	codec Codec
+}
+
+// Decode unmarshals the message body into v using the channel's codec.
+func (m Message) Decode(v any) error {
+	if len(m.Body) == 0 {
+		return nil
+	}
+	codec := m.codec
+	if codec == nil {
+		codec = JSONCodec
+	}
+	return codec.Unmarshal(m.Body, v)
+}
+
+// Channel exchanges discrete, length-prefixed messages over a byte stream,
+// usually the shared memory stream between the host and a panel.
+//
+// Frame layout (big endian):
+//
+//	uint32 length of everything after this field
+//	uint16 length of type
+//	type
+//	body
+//
+// A stream must only be read through one Channel; mixing it with direct
+// reads (e.g. Panel.ReadOutput) corrupts the framing.
+type Channel struct {
+	r     io.Reader
+	w     io.Writer
+	codec Codec
+
+	rmu sync.Mutex
+	// held while a frame is written; a channel so that waiting for it can
+	// be abandoned
+	wsem chan struct{}
+
+	loopOnce sync.Once
+	messages chan Message
+	done     chan struct{}
+	errMu    sync.Mutex
+	err      error
+
+	routeMu sync.RWMutex
+	routes  map[string]func(Message)
+}
+
+// NewChannel wraps rw. codec may be nil, in which case JSONCodec is used.
+func NewChannel(rw io.ReadWriter, codec Codec) *Channel {
+	if codec == nil {
+		codec = JSONCodec
+	}
+	return &Channel{
+		r:        rw,
+		w:        rw,
+		codec:    codec,
+		wsem:     make(chan struct{}, 1),
+		messages: make(chan Message, 16),
+		done:     make(chan struct{}),
+		routes:   map[string]func(Message){},
+	}
+}
+
+// Codec returns the codec used for message bodies.
+func (c *Channel) Codec() Codec {
+	return c.codec
+}
+
+// Send encodes v with the channel's codec and writes it as a single frame.
+// v may be nil for messages without a body.
+func (c *Channel) Send(typ string, v any) error {
+	return c.SendContext(context.Background(), typ, v)
+}
+
+// SendContext is Send bounded by ctx. It stops waiting for the stream, for
+// a panel's handshake or another sender, once ctx is done; a write already
+// under way is not interrupted.
+func (c *Channel) SendContext(ctx context.Context, typ string, v any) error {
+	var body []byte
+	if v != nil {
+		var err error
+		body, err = c.codec.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to encode %q message: %w", typ, err)
+		}
+	}
+	return c.sendRaw(ctx, typ, body)
+}
+
+// SendRaw writes an already encoded body as a single frame.
+func (c *Channel) SendRaw(typ string, body []byte) error {
+	return c.sendRaw(context.Background(), typ, body)
+}
+
+// contextWriter is implemented by streams whose writes may wait before
+// they start, like the host end of a panel stream before the handshake.
+type contextWriter interface {
+	writeContext(ctx context.Context, b []byte) (int, error)
+}
+
+func (c *Channel) sendRaw(ctx context.Context, typ string, body []byte) error {
+	if len(typ) > 0xffff {
+		return fmt.Errorf("message type too long: %d bytes", len(typ))
+	}
+	size := 2 + len(typ) + len(body)
+	if size > MaxMessageSize {
+		return fmt.Errorf("%w: %d bytes", ErrMessageTooLarge, size)
+	}
+
+	frame := make([]byte, 4+size)
+	binary.BigEndian.PutUint32(frame[0:4], uint32(size))
+	binary.BigEndian.PutUint16(frame[4:6], uint16(len(typ)))
+	copy(frame[6:], typ)
+	copy(frame[6+len(typ):], body)
+
+	select {
+	case c.wsem <- struct{}{}:
+	case <-ctx.Done():
+		return fmt.Errorf("failed to write message: %w", ctx.Err())
+	}
+	defer func() { <-c.wsem }()
+
+	var err error
+	if w, ok := c.w.(contextWriter); ok {
+		_, err = w.writeContext(ctx, frame)
+	} else {
+		_, err = c.w.Write(frame)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+// Recv blocks until a whole frame is available and returns it.
+func (c *Channel) Recv() (Message, error) {
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+
+	var header [4]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return Message{}, fmt.Errorf("failed to read message header: %w", err)
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > MaxMessageSize {
+		return Message{}, fmt.Errorf("%w: %d bytes", ErrMessageTooLarge, size)
+	}
+	if size < 2 {
+		return Message{}, fmt.Errorf("invalid frame length %d", size)
+	}
+
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(c.r, frame); err != nil {
+		return Message{}, fmt.Errorf("failed to read message: %w", err)
+	}
+
+	typLen := int(binary.BigEndian.Uint16(frame[0:2]))
+	if 2+typLen > len(frame) {
+		return Message{}, fmt.Errorf("invalid message type length %d", typLen)
+	}
+
+	return Message{
+		Type:  string(frame[2 : 2+typLen]),
+		Body:  frame[2+typLen:],
+		codec: c.codec,
+	}, nil
+}
+
+// Messages starts the receive loop and returns the channel it delivers
+// to. The channel is closed when Recv fails; Err reports why.
+//
+// Reads from shared memory cannot be interrupted, so the loop lives as long
+// as the stream does.
+func (c *Channel) Messages() <-chan Message {
+	c.start()
+	return c.messages
+}
+
+// Route starts the receive loop and delivers messages whose type begins
+// with prefix to fn instead of Messages. When several prefixes match, the
+// longest wins. fn is called from the receive loop and must not block.
+//
+// Messages without a route are buffered for Messages; if nobody reads them
+// the loop, and with it every route, eventually stalls.
+func (c *Channel) Route(prefix string, fn func(Message)) {
+	c.routeMu.Lock()
+	c.routes[prefix] = fn
+	c.routeMu.Unlock()
+
+	c.start()
+}
+
+// Done is closed when the receive loop exits.
+func (c *Channel) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the error that ended the receive loop, if any.
+func (c *Channel) Err() error {
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+
+	return c.err
+}
+
+func (c *Channel) start() {
+	c.loopOnce.Do(func() {
+		go c.receiveLoop()
+	})
+}
+
+func (c *Channel) route(msg Message) bool {
+	c.routeMu.RLock()
+	defer c.routeMu.RUnlock()
+
+	var match string
+	var fn func(Message)
+	for prefix, f := range c.routes {
+		if strings.HasPrefix(msg.Type, prefix) && (fn == nil || len(prefix) > len(match)) {
+			match, fn = prefix, f
+		}
+	}
+	if fn == nil {
+		return false
+	}
+	fn(msg)
+	return true
+}
+
+func (c *Channel) receiveLoop() {
+	defer close(c.done)
+	defer close(c.messages)
+	for {
+		msg, err := c.Recv()
+		if err != nil {
+			c.errMu.Lock()
+			c.err = err
+			c.errMu.Unlock()
+			return
+		}
+		if !c.route(msg) {
+			c.messages <- msg
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// frame builds a raw frame with the given length fields.
+func frame(size uint32, typLen uint16, rest string) []byte {
+	b := binary.BigEndian.AppendUint32(nil, size)
+	b = binary.BigEndian.AppendUint16(b, typLen)
+	return append(b, rest...)
+}
+
+func TestChannelRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	ch := NewChannel(&buf, nil)
+
+	if err := ch.Send("status", map[string]int{"cpu": 42}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.SendRaw("ping", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := ch.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var status struct{ CPU int }
+	if err := msg.Decode(&status); msg.Type != "status" || err != nil || status.CPU != 42 {
+		t.Errorf("message = %s %s, decoded %+v, %v", msg.Type, msg.Body, status, err)
+	}
+
+	msg, err = ch.Recv()
+	if err != nil || msg.Type != "ping" || len(msg.Body) != 0 {
+		t.Errorf("message = %+v, %v", msg, err)
+	}
+}
+
+func TestChannelSendErrors(t *testing.T) {
+	var buf bytes.Buffer
+	ch := NewChannel(&buf, nil)
+
+	if err := ch.SendRaw("big", make([]byte, MaxMessageSize)); !errors.Is(err, ErrMessageTooLarge) {
+		t.Errorf("oversized body: error = %v, want %v", err, ErrMessageTooLarge)
+	}
+	if err := ch.SendRaw(strings.Repeat("t", 0x10000), nil); err == nil {
+		t.Error("oversized type: no error")
+	}
+	if err := ch.Send("bad", func() {}); err == nil {
+		t.Error("unencodable body: no error")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("failed sends wrote %d bytes", buf.Len())
+	}
+}
+
+func TestChannelRecvErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		input []byte
+		want  string
+	}{
+		{"oversized", frame(MaxMessageSize+1, 0, ""), ErrMessageTooLarge.Error()},
+		{"short", frame(1, 0, ""), "invalid frame length 1"},
+		{"type length", frame(4, 5, "ab"), "invalid message type length 5"},
+		{"truncated header", []byte{0, 0}, "failed to read message header"},
+		{"truncated body", frame(10, 2, "ab"), "failed to read message"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := NewChannel(bytes.NewBuffer(tt.input), nil)
+			if _, err := ch.Recv(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestChannelRoute(t *testing.T) {
+	pr, pw := io.Pipe()
+	ch := NewChannel(struct {
+		io.Reader
+		io.Writer
+	}{pr, io.Discard}, nil)
+
+	// routes run on the receive loop, which ends with the pipe
+	var short, long []string
+	ch.Route("rpc:", func(msg Message) { short = append(short, msg.Type) })
+	ch.Route("rpc:call:", func(msg Message) { long = append(long, msg.Type) })
+
+	go func() {
+		w := NewChannel(struct {
+			io.Reader
+			io.Writer
+		}{nil, pw}, nil)
+		for _, typ := range []string{"rpc:call:1:x", "rpc:result:1", "status", "rpc"} {
+			w.SendRaw(typ, nil)
+		}
+		pw.Close()
+	}()
+
+	var rest []string
+	for msg := range ch.Messages() {
+		rest = append(rest, msg.Type)
+	}
+	<-ch.Done()
+
+	if len(long) != 1 || long[0] != "rpc:call:1:x" {
+		t.Errorf("rpc:call: route got %q", long)
+	}
+	if len(short) != 1 || short[0] != "rpc:result:1" {
+		t.Errorf("rpc: route got %q", short)
+	}
+	if len(rest) != 2 || rest[0] != "status" || rest[1] != "rpc" {
+		t.Errorf("Messages got %q", rest)
+	}
+	if err := ch.Err(); err == nil {
+		t.Error("Err() = nil after the stream ended")
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const socketPrefix = "katnip-"
+
+// helper files created next to a panel socket
+var socketSuffixes = []string{"-ready", "-watcher.py", "-rc.conf", "-events"}
+
+// SocketDir returns the directory panel sockets are created in:
+// $XDG_RUNTIME_DIR if set, /tmp otherwise.
+func SocketDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return dir
+	}
+	return "/tmp"
+}
+
+// StaleFiles lists panel sockets and their helper files whose owning
+// process is gone, in SocketDir and in /tmp where older versions put them.
+//
+// Shared memory needs no cleaning: shmstream uses memfd, which the kernel
+// frees with the last process holding it.
+func StaleFiles() ([]string, error) {
+	dirs := []string{SocketDir()}
+	if dirs[0] != "/tmp" {
+		dirs = append(dirs, "/tmp")
+	}
+
+	var stale []string
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return stale, err
+		}
+
+		for _, entry := range entries {
+			pid, ok := socketOwner(entry.Name())
+			if ok && !processAlive(pid) {
+				stale = append(stale, filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+	return stale, nil
+}
+
+// Cleanup removes the files reported by StaleFiles and returns the ones it
+// removed.
+func Cleanup() ([]string, error) {
+	stale, err := StaleFiles()
+
+	removed := make([]string, 0, len(stale))
+	for _, path := range stale {
+		if rerr := os.Remove(path); rerr != nil {
+			if !errors.Is(rerr, os.ErrNotExist) && err == nil {
+				err = rerr
+			}
+			continue
+		}
+		removed = append(removed, path)
+	}
+	return removed, err
+}
+
+// socketOwner parses the host pid out of katnip-<name>-<pid>-<index>, with
+// or without one of socketSuffixes.
+func socketOwner(name string) (int, bool) {
+	if !strings.HasPrefix(name, socketPrefix) {
+		return 0, false
+	}
+	for _, suffix := range socketSuffixes {
+		if base, ok := strings.CutSuffix(name, suffix); ok {
+			name = base
+			break
+		}
+	}
+
+	fields := strings.Split(strings.TrimPrefix(name, socketPrefix), "-")
+	if len(fields) < 3 {
+		return 0, false
+	}
+	if _, err := strconv.ParseUint(fields[len(fields)-1], 10, 64); err != nil {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(fields[len(fields)-2])
+	if err != nil || pid <= 0 {
+		return 0, false
+	}
+	return pid, true
+}
+
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
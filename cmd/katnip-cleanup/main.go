@@ -0,0 +1,56 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Command katnip-cleanup removes panel sockets left behind by katnip
+// processes that are no longer running.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/nekorg/katnip"
+)
+
+func main() {
+	dryRun := flag.Bool("n", false, "only list stale files")
+	quiet := flag.Bool("q", false, "do not list removed files")
+	flag.Parse()
+
+	var (
+		files []string
+		err   error
+	)
+	if *dryRun {
+		files, err = katnip.StaleFiles()
+	} else {
+		files, err = katnip.Cleanup()
+	}
+
+	if !*quiet || *dryRun {
+		for _, f := range files {
+			fmt.Println(f)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "katnip-cleanup: %v\n", err)
+		os.Exit(1)
+	}
+}
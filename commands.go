@@ -0,0 +1,366 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// Request is a typed payload for a kitty remote-control command.
+// Cmd returns the command name as understood by kitty, e.g. "ls".
+type Request interface {
+	Cmd() string
+}
+
+// decodeData decodes the "data" field of a response into out.
+//
+// Some commands (ls, launch) return their data as a JSON encoded string,
+// so a string is unwrapped once when out is not itself a string.
+func decodeData(raw json.RawMessage, out any) error {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	if _, isString := out.(*string); !isString && raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return fmt.Errorf("failed to decode response data: %w", err)
+		}
+		raw = json.RawMessage(s)
+	}
+
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("failed to decode response data: %w", err)
+	}
+	return nil
+}
+
+// LsRequest lists OS windows, tabs and windows. Response: []OSWindow
+type LsRequest struct {
+	Match      string `json:"match,omitempty"`
+	MatchTab   string `json:"match_tab,omitempty"`
+	AllEnvVars bool   `json:"all_env_vars,omitempty"`
+	Self       bool   `json:"self,omitempty"`
+}
+
+func (LsRequest) Cmd() string { return "ls" }
+
+// SetColorsRequest changes terminal colors. Colors maps color names
+// (foreground, background, color0...) to 0xRRGGBB values.
+type SetColorsRequest struct {
+	Colors      map[string]uint32 `json:"colors"`
+	MatchWindow string            `json:"match_window,omitempty"`
+	MatchTab    string            `json:"match_tab,omitempty"`
+	All         bool              `json:"all,omitempty"`
+	Configured  bool              `json:"configured,omitempty"`
+	Reset       bool              `json:"reset,omitempty"`
+}
+
+func (SetColorsRequest) Cmd() string { return "set-colors" }
+
+// SendTextRequest sends Text to the matched windows as if it was typed.
+type SendTextRequest struct {
+	Text           string `json:"-"`
+	Match          string `json:"match,omitempty"`
+	MatchTab       string `json:"match_tab,omitempty"`
+	All            bool   `json:"all,omitempty"`
+	ExcludeActive  bool   `json:"exclude_active,omitempty"`
+	BracketedPaste string `json:"bracketed_paste,omitempty"`
+}
+
+func (SendTextRequest) Cmd() string { return "send-text" }
+
+func (r SendTextRequest) MarshalJSON() ([]byte, error) {
+	type plain SendTextRequest
+	return json.Marshal(struct {
+		plain
+		Data string `json:"data"`
+	}{plain(r), "base64:" + base64.StdEncoding.EncodeToString([]byte(r.Text))})
+}
+
+// GetTextRequest reads text from a window. Response: string
+type GetTextRequest struct {
+	Match          string `json:"match,omitempty"`
+	Extent         string `json:"extent,omitempty"`
+	Ansi           bool   `json:"ansi,omitempty"`
+	Cursor         bool   `json:"cursor,omitempty"`
+	WrapMarkers    bool   `json:"wrap_markers,omitempty"`
+	ClearSelection bool   `json:"clear_selection,omitempty"`
+	Self           bool   `json:"self,omitempty"`
+}
+
+func (GetTextRequest) Cmd() string { return "get-text" }
+
+type SetWindowTitleRequest struct {
+	Title     string `json:"title"`
+	Match     string `json:"match,omitempty"`
+	Temporary bool   `json:"temporary,omitempty"`
+}
+
+func (SetWindowTitleRequest) Cmd() string { return "set-window-title" }
+
+type SetTabTitleRequest struct {
+	Title string `json:"title"`
+	Match string `json:"match,omitempty"`
+}
+
+func (SetTabTitleRequest) Cmd() string { return "set-tab-title" }
+
+// LaunchRequest opens a new window, tab or OS window. Response: window id (int)
+type LaunchRequest struct {
+	Args        []string `json:"args,omitempty"`
+	Match       string   `json:"match,omitempty"`
+	Type        string   `json:"type,omitempty"`
+	WindowTitle string   `json:"window_title,omitempty"`
+	TabTitle    string   `json:"tab_title,omitempty"`
+	Cwd         string   `json:"cwd,omitempty"`
+	// entries of the form KEY=VALUE
+	Env                []string `json:"env,omitempty"`
+	Location           string   `json:"location,omitempty"`
+	KeepFocus          bool     `json:"keep_focus,omitempty"`
+	CopyEnv            bool     `json:"copy_env,omitempty"`
+	Hold               bool     `json:"hold,omitempty"`
+	AllowRemoteControl bool     `json:"allow_remote_control,omitempty"`
+	Self               bool     `json:"self,omitempty"`
+	Watcher            []string `json:"watcher,omitempty"`
+}
+
+func (LaunchRequest) Cmd() string { return "launch" }
+
+type CloseWindowRequest struct {
+	Match         string `json:"match,omitempty"`
+	Self          bool   `json:"self,omitempty"`
+	IgnoreNoMatch bool   `json:"ignore_no_match,omitempty"`
+}
+
+func (CloseWindowRequest) Cmd() string { return "close-window" }
+
+type CloseTabRequest struct {
+	Match         string `json:"match,omitempty"`
+	Self          bool   `json:"self,omitempty"`
+	IgnoreNoMatch bool   `json:"ignore_no_match,omitempty"`
+}
+
+func (CloseTabRequest) Cmd() string { return "close-tab" }
+
+type FocusWindowRequest struct {
+	Match string `json:"match,omitempty"`
+}
+
+func (FocusWindowRequest) Cmd() string { return "focus-window" }
+
+type SignalChildRequest struct {
+	Signals []string `json:"signals"`
+	Match   string   `json:"match,omitempty"`
+}
+
+func (SignalChildRequest) Cmd() string { return "signal-child" }
+
+// SetFontSizeRequest sets the font size. IncrementOp is one of "+", "-",
+// "*", "/" to change the size relative to the current one.
+type SetFontSizeRequest struct {
+	Size        float64 `json:"size"`
+	IncrementOp string  `json:"increment_op,omitempty"`
+}
+
+func (SetFontSizeRequest) Cmd() string { return "set-font-size" }
+
+type SetBackgroundOpacityRequest struct {
+	Opacity     float64 `json:"opacity"`
+	MatchWindow string  `json:"match_window,omitempty"`
+	MatchTab    string  `json:"match_tab,omitempty"`
+	All         bool    `json:"all,omitempty"`
+	Toggle      bool    `json:"toggle,omitempty"`
+}
+
+func (SetBackgroundOpacityRequest) Cmd() string { return "set-background-opacity" }
+
+// ResizeOSWindowRequest resizes, shows or hides an OS window. For panels,
+// Action "os-panel" with OSPanel entries of the form "option=value" changes
+// panel options in place.
+type ResizeOSWindowRequest struct {
+	Match       string   `json:"match,omitempty"`
+	Action      string   `json:"action,omitempty"`
+	Unit        string   `json:"unit,omitempty"`
+	Incremental bool     `json:"incremental,omitempty"`
+	Width       int      `json:"width,omitempty"`
+	Height      int      `json:"height,omitempty"`
+	Self        bool     `json:"self,omitempty"`
+	OSPanel     []string `json:"os_panel,omitempty"`
+}
+
+func (ResizeOSWindowRequest) Cmd() string { return "resize-os-window" }
+
+type OSWindow struct {
+	ID                int     `json:"id"`
+	PlatformWindowID  int     `json:"platform_window_id"`
+	IsActive          bool    `json:"is_active"`
+	IsFocused         bool    `json:"is_focused"`
+	LastFocused       bool    `json:"last_focused"`
+	WMClass           string  `json:"wm_class"`
+	WMName            string  `json:"wm_name"`
+	BackgroundOpacity float64 `json:"background_opacity"`
+	Tabs              []Tab   `json:"tabs"`
+}
+
+type Tab struct {
+	ID                  int      `json:"id"`
+	IsActive            bool     `json:"is_active"`
+	IsFocused           bool     `json:"is_focused"`
+	Title               string   `json:"title"`
+	Layout              string   `json:"layout"`
+	ActiveWindowHistory []int    `json:"active_window_history"`
+	Windows             []Window `json:"windows"`
+}
+
+type Window struct {
+	ID                  int               `json:"id"`
+	IsActive            bool              `json:"is_active"`
+	IsFocused           bool              `json:"is_focused"`
+	IsSelf              bool              `json:"is_self"`
+	Title               string            `json:"title"`
+	PID                 int               `json:"pid"`
+	Cwd                 string            `json:"cwd"`
+	Cmdline             []string          `json:"cmdline"`
+	Env                 map[string]string `json:"env"`
+	UserVars            map[string]string `json:"user_vars"`
+	Lines               int               `json:"lines"`
+	Columns             int               `json:"columns"`
+	AtPrompt            bool              `json:"at_prompt"`
+	ForegroundProcesses []Process         `json:"foreground_processes"`
+}
+
+type Process struct {
+	PID     int      `json:"pid"`
+	Cwd     string   `json:"cwd"`
+	Cmdline []string `json:"cmdline"`
+}
+
+func (k *Kitty) Ls(req LsRequest) ([]OSWindow, error) {
+	return k.LsContext(context.Background(), req)
+}
+
+func (k *Kitty) LsContext(ctx context.Context, req LsRequest) ([]OSWindow, error) {
+	var windows []OSWindow
+	if err := k.DoContext(ctx, req, &windows); err != nil {
+		return nil, err
+	}
+	return windows, nil
+}
+
+func (k *Kitty) SetColors(req SetColorsRequest) error {
+	return k.SetColorsContext(context.Background(), req)
+}
+
+func (k *Kitty) SetColorsContext(ctx context.Context, req SetColorsRequest) error {
+	return k.DoContext(ctx, req, nil)
+}
+
+func (k *Kitty) SendText(req SendTextRequest) error {
+	return k.SendTextContext(context.Background(), req)
+}
+
+func (k *Kitty) SendTextContext(ctx context.Context, req SendTextRequest) error {
+	return k.DoContext(ctx, req, nil)
+}
+
+func (k *Kitty) GetText(req GetTextRequest) (string, error) {
+	return k.GetTextContext(context.Background(), req)
+}
+
+func (k *Kitty) GetTextContext(ctx context.Context, req GetTextRequest) (string, error) {
+	var text string
+	if err := k.DoContext(ctx, req, &text); err != nil {
+		return "", err
+	}
+	return text, nil
+}
+
+func (k *Kitty) SetWindowTitle(req SetWindowTitleRequest) error {
+	return k.SetWindowTitleContext(context.Background(), req)
+}
+
+func (k *Kitty) SetWindowTitleContext(ctx context.Context, req SetWindowTitleRequest) error {
+	return k.DoContext(ctx, req, nil)
+}
+
+func (k *Kitty) SetTabTitle(req SetTabTitleRequest) error {
+	return k.SetTabTitleContext(context.Background(), req)
+}
+
+func (k *Kitty) SetTabTitleContext(ctx context.Context, req SetTabTitleRequest) error {
+	return k.DoContext(ctx, req, nil)
+}
+
+// Launch returns the id of the newly created window.
+func (k *Kitty) Launch(req LaunchRequest) (int, error) {
+	return k.LaunchContext(context.Background(), req)
+}
+
+func (k *Kitty) LaunchContext(ctx context.Context, req LaunchRequest) (int, error) {
+	var id int
+	if err := k.DoContext(ctx, req, &id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (k *Kitty) CloseWindow(req CloseWindowRequest) error {
+	return k.CloseWindowContext(context.Background(), req)
+}
+
+func (k *Kitty) CloseWindowContext(ctx context.Context, req CloseWindowRequest) error {
+	return k.DoContext(ctx, req, nil)
+}
+
+func (k *Kitty) CloseTab(req CloseTabRequest) error {
+	return k.CloseTabContext(context.Background(), req)
+}
+
+func (k *Kitty) CloseTabContext(ctx context.Context, req CloseTabRequest) error {
+	return k.DoContext(ctx, req, nil)
+}
+
+func (k *Kitty) FocusWindow(req FocusWindowRequest) error {
+	return k.FocusWindowContext(context.Background(), req)
+}
+
+func (k *Kitty) FocusWindowContext(ctx context.Context, req FocusWindowRequest) error {
+	return k.DoContext(ctx, req, nil)
+}
+
+func (k *Kitty) SignalChild(req SignalChildRequest) error {
+	return k.SignalChildContext(context.Background(), req)
+}
+
+func (k *Kitty) SignalChildContext(ctx context.Context, req SignalChildRequest) error {
+	return k.DoContext(ctx, req, nil)
+}
+
+func (k *Kitty) ResizeOSWindow(req ResizeOSWindowRequest) error {
+	return k.ResizeOSWindowContext(context.Background(), req)
+}
+
+func (k *Kitty) ResizeOSWindowContext(ctx context.Context, req ResizeOSWindowRequest) error {
+	return k.DoContext(ctx, req, nil)
+}
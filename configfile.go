@@ -0,0 +1,447 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// values accepted by the parsers below, by name as emitted by String
+var (
+	layers        = []Layer{LayerBackground, LayerBottom, LayerTop, LayerOverlay}
+	focusPolicies = []FocusPolicy{FocusExclusive, FocusNotAllowed, FocusOnDemand}
+	edges         = []Edge{
+		EdgeBackground, EdgeBottom, EdgeCenter, EdgeCenterSized,
+		EdgeLeft, EdgeNone, EdgeRight, EdgeTop,
+	}
+)
+
+func parseEnum[T fmt.Stringer](kind, s string, values []T) (T, error) {
+	names := make([]string, len(values))
+	for i, v := range values {
+		if v.String() == s {
+			return v, nil
+		}
+		names[i] = v.String()
+	}
+	var zero T
+	return zero, fmt.Errorf("unknown %s %q, expected one of: %s", kind, s, strings.Join(names, ", "))
+}
+
+// ParseLayer parses the names emitted by Layer.String.
+func ParseLayer(s string) (Layer, error) {
+	return parseEnum("layer", s, layers)
+}
+
+// ParseFocusPolicy parses the names emitted by FocusPolicy.String.
+func ParseFocusPolicy(s string) (FocusPolicy, error) {
+	return parseEnum("focus policy", s, focusPolicies)
+}
+
+// ParseEdge parses the names emitted by Edge.String.
+func ParseEdge(s string) (Edge, error) {
+	return parseEnum("edge", s, edges)
+}
+
+// The zero value of Layer, FocusPolicy and Edge means "kitty's default"
+// and is marshalled as an empty string.
+
+func (l Layer) MarshalText() ([]byte, error) {
+	if l == 0 {
+		return nil, nil
+	}
+	return []byte(l.String()), nil
+}
+
+func (l *Layer) UnmarshalText(b []byte) (err error) {
+	if len(b) == 0 {
+		*l = 0
+		return nil
+	}
+	*l, err = ParseLayer(string(b))
+	return err
+}
+
+func (f FocusPolicy) MarshalText() ([]byte, error) {
+	if f == 0 {
+		return nil, nil
+	}
+	return []byte(f.String()), nil
+}
+
+func (f *FocusPolicy) UnmarshalText(b []byte) (err error) {
+	if len(b) == 0 {
+		*f = 0
+		return nil
+	}
+	*f, err = ParseFocusPolicy(string(b))
+	return err
+}
+
+func (e Edge) MarshalText() ([]byte, error) {
+	if e == 0 {
+		return nil, nil
+	}
+	return []byte(e.String()), nil
+}
+
+func (e *Edge) UnmarshalText(b []byte) (err error) {
+	if len(b) == 0 {
+		*e = 0
+		return nil
+	}
+	*e, err = ParseEdge(string(b))
+	return err
+}
+
+// PanelSpec is a panel defined in a config file.
+type PanelSpec struct {
+	// key of the panel in the file
+	Name string
+	// registered handler to run, default: Name
+	Handler string
+	Config  Config
+}
+
+// NewPanel creates the panel named Name running Handler, failing if the
+// handler is not registered.
+func (s PanelSpec) NewPanel() (*Panel, error) {
+	if _, ok := registry[s.Handler]; !ok {
+		return nil, fmt.Errorf("panel %q: handler %q is not registered", s.Name, s.Handler)
+	}
+	return newPanel(s.Name, s.Handler, s.Config), nil
+}
+
+// ConfigError points to the key of a config file that failed validation.
+type ConfigError struct {
+	File string
+	Key  string
+	Err  error
+}
+
+func (e *ConfigError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("%s: %v", e.Key, e.Err)
+	}
+	return fmt.Sprintf("%s: %s: %v", e.File, e.Key, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// LoadConfigFile reads panel definitions from a JSON (.json) or TOML (.toml)
+// file. Panels are keyed by name under "panels":
+//
+//	[panels.bar]
+//	handler = "statusbar"
+//	edge = "top"
+//	size = { y = 1 }
+//
+// Keys are the snake_case names of the Config fields. Edge, layer and
+// focus_policy take the same names kitty does, e.g. "center-sized". args
+// may hold any value and reaches the handler through Instance.Args.
+func LoadConfigFile(path string) ([]PanelSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var format string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		format = "json"
+	case ".toml":
+		format = "toml"
+	default:
+		return nil, fmt.Errorf("%s: unsupported config format, expected .json or .toml", path)
+	}
+
+	specs, err := ParseConfig(data, format)
+	if cerr, ok := err.(*ConfigError); ok {
+		cerr.File = path
+	}
+	return specs, err
+}
+
+// ParseConfig parses panel definitions, see LoadConfigFile. format is
+// "json" or "toml". Panels are returned sorted by name.
+func ParseConfig(data []byte, format string) ([]PanelSpec, error) {
+	var doc map[string]any
+	switch format {
+	case "json":
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("invalid json: %w", err)
+		}
+	case "toml":
+		if err := toml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("invalid toml: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config format %q", format)
+	}
+
+	for key := range doc {
+		if key != "panels" {
+			return nil, &ConfigError{Key: key, Err: fmt.Errorf("unknown key")}
+		}
+	}
+
+	panels, ok := doc["panels"].(map[string]any)
+	if !ok {
+		return nil, &ConfigError{Key: "panels", Err: fmt.Errorf("expected a table of panels")}
+	}
+
+	names := make([]string, 0, len(panels))
+	for name := range panels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	specs := make([]PanelSpec, 0, len(names))
+	for _, name := range names {
+		d := configDecoder{prefix: "panels." + name}
+		spec := d.panel(name, panels[name])
+		if d.err != nil {
+			return nil, d.err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// configDecoder walks the generic maps produced by the json and toml
+// decoders, keeping the first error along with the key it occurred at.
+type configDecoder struct {
+	prefix string
+	err    error
+}
+
+func (d *configDecoder) fail(key string, format string, args ...any) {
+	if d.err == nil {
+		d.err = &ConfigError{Key: d.prefix + "." + key, Err: fmt.Errorf(format, args...)}
+	}
+}
+
+func (d *configDecoder) panel(name string, v any) PanelSpec {
+	spec := PanelSpec{Name: name, Handler: name}
+
+	m, ok := v.(map[string]any)
+	if !ok {
+		d.err = &ConfigError{Key: d.prefix, Err: fmt.Errorf("expected a table")}
+		return spec
+	}
+
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	c := &spec.Config
+	for _, key := range keys {
+		value := m[key]
+		switch key {
+		case "handler":
+			spec.Handler = d.string(key, value)
+		case "position":
+			c.Position = d.vector(key, value)
+		case "size":
+			c.Size = d.vector(key, value)
+		case "margins":
+			c.Margins = d.margins(key, value)
+		case "layer":
+			c.Layer = d.enumLayer(key, value)
+		case "focus_policy":
+			c.FocusPolicy = d.enumFocusPolicy(key, value)
+		case "edge":
+			c.Edge = d.enumEdge(key, value)
+		case "output_name":
+			c.OutputName = d.string(key, value)
+		case "class":
+			c.Class = d.string(key, value)
+		case "hide_on_focus_loss":
+			c.HideOnFocusLoss = d.bool(key, value)
+		case "start_as_hidden":
+			c.StartAsHidden = d.bool(key, value)
+		case "single_instance":
+			c.SingleInstance = d.bool(key, value)
+		case "instance_group":
+			c.InstanceGroup = d.string(key, value)
+		case "watcher":
+			c.Watcher = d.bool(key, value)
+		case "config_file":
+			c.ConfigFile = d.string(key, value)
+		case "overrides":
+			c.Overrides = d.stringMap(key, value)
+		case "kitty_overrides":
+			c.KittyOverrides = d.stringList(key, value)
+		case "kitty_cmd":
+			c.KittyCmd = d.string(key, value)
+		case "remote_control_password":
+			c.RemoteControlPassword = d.string(key, value)
+		case "remote_control_actions":
+			c.RemoteControlActions = d.stringList(key, value)
+		case "args":
+			c.Args = value
+		default:
+			d.fail(key, "unknown key")
+		}
+	}
+
+	if spec.Handler == "" {
+		d.fail("handler", "must not be empty")
+	}
+	return spec
+}
+
+func (d *configDecoder) string(key string, v any) string {
+	s, ok := v.(string)
+	if !ok {
+		d.fail(key, "expected a string, got %T", v)
+	}
+	return s
+}
+
+func (d *configDecoder) bool(key string, v any) bool {
+	b, ok := v.(bool)
+	if !ok {
+		d.fail(key, "expected a boolean, got %T", v)
+	}
+	return b
+}
+
+func (d *configDecoder) int(key string, v any) int {
+	switch n := v.(type) {
+	case int64:
+		return int(n)
+	case float64:
+		if n == float64(int(n)) {
+			return int(n)
+		}
+	}
+	d.fail(key, "expected an integer, got %v", v)
+	return 0
+}
+
+func (d *configDecoder) vector(key string, v any) Vector {
+	m, ok := v.(map[string]any)
+	if !ok {
+		d.fail(key, "expected a table with x and y")
+		return Vector{}
+	}
+
+	var vec Vector
+	for k, value := range m {
+		switch k {
+		case "x":
+			vec.X = d.int(key+".x", value)
+		case "y":
+			vec.Y = d.int(key+".y", value)
+		default:
+			d.fail(key+"."+k, "unknown key")
+		}
+	}
+	return vec
+}
+
+func (d *configDecoder) margins(key string, v any) Margins {
+	m, ok := v.(map[string]any)
+	if !ok {
+		d.fail(key, "expected a table with top, bottom, left and right")
+		return Margins{}
+	}
+
+	var margins Margins
+	for k, value := range m {
+		switch k {
+		case "top":
+			margins.Top = d.int(key+".top", value)
+		case "bottom":
+			margins.Bottom = d.int(key+".bottom", value)
+		case "left":
+			margins.Left = d.int(key+".left", value)
+		case "right":
+			margins.Right = d.int(key+".right", value)
+		default:
+			d.fail(key+"."+k, "unknown key")
+		}
+	}
+	return margins
+}
+
+func (d *configDecoder) stringList(key string, v any) []string {
+	list, ok := v.([]any)
+	if !ok {
+		d.fail(key, "expected a list of strings")
+		return nil
+	}
+
+	out := make([]string, len(list))
+	for i, item := range list {
+		out[i] = d.string(fmt.Sprintf("%s[%d]", key, i), item)
+	}
+	return out
+}
+
+func (d *configDecoder) stringMap(key string, v any) map[string]string {
+	m, ok := v.(map[string]any)
+	if !ok {
+		d.fail(key, "expected a table of strings")
+		return nil
+	}
+
+	out := make(map[string]string, len(m))
+	for k, value := range m {
+		out[k] = d.string(key+"."+k, value)
+	}
+	return out
+}
+
+func (d *configDecoder) enumLayer(key string, v any) Layer {
+	l, err := ParseLayer(d.string(key, v))
+	if err != nil {
+		d.fail(key, "%v", err)
+	}
+	return l
+}
+
+func (d *configDecoder) enumFocusPolicy(key string, v any) FocusPolicy {
+	f, err := ParseFocusPolicy(d.string(key, v))
+	if err != nil {
+		d.fail(key, "%v", err)
+	}
+	return f
+}
+
+func (d *configDecoder) enumEdge(key string, v any) Edge {
+	e, err := ParseEdge(d.string(key, v))
+	if err != nil {
+		d.fail(key, "%v", err)
+	}
+	return e
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseEnum(t *testing.T) {
+	for _, e := range edges {
+		if got, err := ParseEdge(e.String()); err != nil || got != e {
+			t.Errorf("ParseEdge(%q) = %v, %v", e.String(), got, err)
+		}
+	}
+	for _, l := range layers {
+		if got, err := ParseLayer(l.String()); err != nil || got != l {
+			t.Errorf("ParseLayer(%q) = %v, %v", l.String(), got, err)
+		}
+	}
+	for _, f := range focusPolicies {
+		if got, err := ParseFocusPolicy(f.String()); err != nil || got != f {
+			t.Errorf("ParseFocusPolicy(%q) = %v, %v", f.String(), got, err)
+		}
+	}
+
+	// stringer's fallback for unknown values must not parse
+	for _, s := range []string{"", "Edge(0)", "Edge(9)", "Top"} {
+		if got, err := ParseEdge(s); err == nil {
+			t.Errorf("ParseEdge(%q) = %v, want error", s, got)
+		}
+	}
+	_, err := ParseLayer("middle")
+	if err == nil || !strings.Contains(err.Error(), "background, bottom, top, overlay") {
+		t.Errorf("ParseLayer(middle) error = %v", err)
+	}
+}
+
+func TestPanelSpecNewPanel(t *testing.T) {
+	registry["test-handler"] = PanelFunc(func(*Kitty, io.ReadWriter) int { return 0 })
+	defer delete(registry, "test-handler")
+
+	specs, err := ParseConfig([]byte(`
+[panels.left]
+handler = "test-handler"
+edge = "left"
+
+[panels.right]
+handler = "missing"
+`), "toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := specs[0].NewPanel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Shutdown(t.Context())
+
+	if p.Name() != "left" {
+		t.Errorf("Name() = %q, want left", p.Name())
+	}
+	if !slices.Contains(p.Cmd.Env, GetEnvPair("INSTANCE", "test-handler")) {
+		t.Errorf("env lacks the handler: %v", p.Cmd.Env)
+	}
+	if !slices.Contains(p.Cmd.Args, "left") {
+		t.Errorf("class is not the panel name: %v", p.Cmd.Args)
+	}
+
+	if _, err := specs[1].NewPanel(); err == nil {
+		t.Error("NewPanel with an unregistered handler succeeded")
+	}
+}
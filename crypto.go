@@ -0,0 +1,138 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nekorg/katnip/internal/base85"
+)
+
+// encryptedMsg is the envelope kitty expects for commands carrying a
+// remote_control_password. The command, with password and timestamp added,
+// is encrypted with AES-256-GCM under the SHA-256 of an X25519 secret
+// shared with kitty's public key.
+type encryptedMsg struct {
+	Version   [3]uint64 `json:"version"`
+	IV        string    `json:"iv"`
+	Tag       string    `json:"tag"`
+	Pubkey    string    `json:"pubkey"`
+	Encrypted string    `json:"encrypted"`
+	EncProto  string    `json:"enc_proto"`
+}
+
+const encProto = "1"
+
+// SetPassword sets the remote_control_password sent with every command.
+// Commands are then encrypted, which needs kitty's public key, see
+// SetPublicKey. An empty password sends plain commands again.
+func (k *Kitty) SetPassword(password string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.password = password
+}
+
+// SetPublicKey sets the public key of the kitty instance, in the form
+// kitty exports in KITTY_PUBLIC_KEY ("1:<base85 key>"). Inside a panel it
+// is read from the environment; the client from Panel.Kitty takes it from
+// the handshake.
+func (k *Kitty) SetPublicKey(key string) error {
+	pub, err := parsePublicKey(key)
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.publicKey = pub
+	return nil
+}
+
+func parsePublicKey(key string) (*ecdh.PublicKey, error) {
+	proto, encoded, ok := strings.Cut(key, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid kitty public key")
+	}
+	if proto != encProto {
+		return nil, fmt.Errorf("unsupported kitty public key protocol %q", proto)
+	}
+
+	raw, err := base85.Decode(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kitty public key: %w", err)
+	}
+	pub, err := ecdh.X25519().NewPublicKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kitty public key: %w", err)
+	}
+	return pub, nil
+}
+
+// encrypt wraps msg, a marshalled kittySockMsg, for kitty's public key.
+func encrypt(msg []byte, kittyKey *ecdh.PublicKey) ([]byte, error) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := priv.ECDH(kittyKey)
+	if err != nil {
+		return nil, err
+	}
+	key := sha256.Sum256(secret)
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	sealed := gcm.Seal(nil, iv, msg, nil)
+	ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+	return json.Marshal(encryptedMsg{
+		Version:   kittyMinVersion,
+		IV:        base85.Encode(iv),
+		Tag:       base85.Encode(tag),
+		Pubkey:    base85.Encode(priv.PublicKey().Bytes()),
+		Encrypted: base85.Encode(ciphertext),
+		EncProto:  encProto,
+	})
+}
+
+func timestamp() int64 {
+	return time.Now().UnixNano()
+}
@@ -0,0 +1,197 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/sha256"
+	"encoding/json"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nekorg/katnip/internal/base85"
+)
+
+// testKey stands in for the key of a kitty instance.
+func testKey(t *testing.T) *ecdh.PrivateKey {
+	t.Helper()
+
+	seed := make([]byte, 32)
+	for i := range seed {
+		seed[i] = byte(i + 1)
+	}
+	key, err := ecdh.X25519().NewPrivateKey(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// decrypt opens an encryptedMsg the way kitty does.
+func decrypt(t *testing.T, data []byte, key *ecdh.PrivateKey) []byte {
+	t.Helper()
+
+	var env encryptedMsg
+	if err := json.Unmarshal(data, &env); err != nil {
+		t.Fatal(err)
+	}
+	if env.EncProto != encProto || env.Version != kittyMinVersion {
+		t.Fatalf("envelope = %+v", env)
+	}
+
+	decode := func(s string) []byte {
+		b, err := base85.Decode(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	pub, err := ecdh.X25519().NewPublicKey(decode(env.Pubkey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := key.ECDH(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aesKey := sha256.Sum256(secret)
+	block, err := aes.NewCipher(aesKey[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain, err := gcm.Open(nil, decode(env.IV), append(decode(env.Encrypted), decode(env.Tag)...), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plain
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	key := testKey(t)
+	msg := []byte(`{"cmd":"ls","version":[0,42,0],"password":"secret","timestamp":1}`)
+
+	first, err := encrypt(msg, key.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decrypt(t, first, key); !bytes.Equal(got, msg) {
+		t.Errorf("decrypted %s, want %s", got, msg)
+	}
+	if bytes.Contains(first, []byte("secret")) {
+		t.Errorf("password in the clear: %s", first)
+	}
+
+	// a fresh key and iv for every message
+	second, err := encrypt(msg, key.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(first, second) {
+		t.Error("two encryptions of the same message are identical")
+	}
+}
+
+func TestEncodeCommandEncrypted(t *testing.T) {
+	key := testKey(t)
+
+	k := NewKitty("")
+	if err := k.SetPublicKey("1:" + base85.Encode(key.PublicKey().Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{"", "1", "2:abc", "1:abc"} {
+		if err := k.SetPublicKey(bad); err == nil {
+			t.Errorf("SetPublicKey(%q) succeeded", bad)
+		}
+	}
+
+	k.SetPassword("secret")
+	frame, err := k.encodeCommand("set-font-size", map[string]int{"size": 12}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := bytes.TrimSuffix(bytes.TrimPrefix(frame, kittyMsgPrefix), kittyMsgSuffix)
+
+	var msg kittySockMsg
+	if err := json.Unmarshal(decrypt(t, body, key), &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Command != "set-font-size" || msg.Password != "secret" || msg.Timestamp == 0 {
+		t.Errorf("decrypted message = %+v", msg)
+	}
+	if string(msg.Payload) != `{"size":12}` {
+		t.Errorf("payload = %s", msg.Payload)
+	}
+
+	k.SetPassword("")
+	frame, err = k.encodeCommand("ls", nil, false)
+	if err != nil || !bytes.Contains(frame, []byte(`"cmd":"ls"`)) {
+		t.Errorf("plain command = %q, %v", frame, err)
+	}
+}
+
+func TestPasswordNotExposed(t *testing.T) {
+	p := NewPanel("secret-test", Config{
+		RemoteControlPassword: "hunter 2",
+		RemoteControlActions:  []string{"ls", "set-*"},
+		// exits right away, enough to get the config written
+		KittyCmd: "true",
+	})
+	defer p.Shutdown(t.Context())
+
+	if slices.ContainsFunc(p.Cmd.Args, func(a string) bool { return strings.Contains(a, "hunter") }) {
+		t.Errorf("password on the command line: %q", p.Cmd.Args)
+	}
+	if slices.ContainsFunc(p.Cmd.Env, func(e string) bool { return strings.Contains(e, "hunter") }) {
+		t.Errorf("password in the environment: %q", p.Cmd.Env)
+	}
+	if !slices.Contains(p.Cmd.Args, p.rcConfigPath()) {
+		t.Errorf("config not passed to kitty: %q", p.Cmd.Args)
+	}
+
+	if err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(p.rcConfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("config mode = %v, want 0600", info.Mode())
+	}
+	conf, _ := os.ReadFile(p.rcConfigPath())
+	if want := "remote_control_password 'hunter 2' 'ls' 'set-*'\n"; !strings.HasSuffix(string(conf), want) {
+		t.Errorf("config = %q, want suffix %q", conf, want)
+	}
+
+	p.Shutdown(t.Context())
+	if _, err := os.Stat(p.rcConfigPath()); !os.IsNotExist(err) {
+		t.Errorf("config left behind: %v", err)
+	}
+}
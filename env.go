@@ -19,7 +19,10 @@
 
 package katnip
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 const envname = "KATNIP"
 
@@ -37,3 +40,19 @@ func GetEnvKey(name string) string {
 func GetEnvPair(name, value string) string {
 	return fmt.Sprintf("%s=%s", GetEnvKey(name), value)
 }
+
+// setEnvPair replaces the entries for name in env with one set to value,
+// or removes them if value is empty.
+func setEnvPair(env []string, name, value string) []string {
+	prefix := GetEnvKey(name) + "="
+	out := env[:0:0]
+	for _, kv := range env {
+		if !strings.HasPrefix(kv, prefix) {
+			out = append(out, kv)
+		}
+	}
+	if value != "" {
+		out = append(out, GetEnvPair(name, value))
+	}
+	return out
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSetEnvPair(t *testing.T) {
+	env := []string{"HOME=/root", "KATNIP_ARGS=1", "KATNIP_ARGSX=2", "KATNIP_ARGS=3"}
+
+	got := setEnvPair(env, "ARGS", "4")
+	if want := []string{"HOME=/root", "KATNIP_ARGSX=2", "KATNIP_ARGS=4"}; !slices.Equal(got, want) {
+		t.Errorf("set: env = %q, want %q", got, want)
+	}
+	got = setEnvPair(got, "ARGS", "")
+	if want := []string{"HOME=/root", "KATNIP_ARGSX=2"}; !slices.Equal(got, want) {
+		t.Errorf("unset: env = %q, want %q", got, want)
+	}
+	if env[1] != "KATNIP_ARGS=1" {
+		t.Error("env was modified in place")
+	}
+}
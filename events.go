@@ -0,0 +1,398 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+//go:generate stringer -type=WindowEventKind -linecomment -output events_string.go
+package katnip
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type WindowEventKind int
+
+const (
+	WindowFocusIn  WindowEventKind = iota + 1 // focus-in
+	WindowFocusOut                            // focus-out
+	WindowResized                             // resized
+	WindowHidden                              // hidden
+	WindowShown                               // shown
+	WindowClosed                              // closed
+)
+
+type WindowEvent struct {
+	Kind     WindowEventKind
+	WindowID int
+
+	// set for WindowResized
+	Columns, Lines int
+}
+
+// EventPollInterval is how often ls is polled for changes when no kitty
+// watcher is installed (see Config.Watcher).
+var EventPollInterval = 500 * time.Millisecond
+
+// watcherScript is loaded by kitty when Config.Watcher is set. It forwards
+// window events to the socket in KATNIP_EVENTS, which the panel listens on.
+const watcherScript = `# generated by katnip
+import json
+import os
+import socket
+
+
+def _send(event):
+    path = os.environ.get('KATNIP_EVENTS')
+    if not path:
+        return
+    try:
+        s = socket.socket(socket.AF_UNIX, socket.SOCK_STREAM)
+        s.settimeout(0.2)
+        s.connect(path)
+        s.sendall((json.dumps(event) + '\n').encode())
+        s.close()
+    except OSError:
+        pass
+
+
+def on_focus_change(boss, window, data):
+    _send({'event': 'focus', 'window_id': window.id, 'focused': bool(data['focused'])})
+
+
+def on_resize(boss, window, data):
+    g = data['new_geometry']
+    _send({'event': 'resize', 'window_id': window.id, 'columns': g.xnum, 'lines': g.ynum})
+
+
+def on_close(boss, window, data):
+    _send({'event': 'close', 'window_id': window.id})
+`
+
+type watcherEvent struct {
+	Event    string `json:"event"`
+	WindowID int    `json:"window_id"`
+	Focused  bool   `json:"focused"`
+	Columns  int    `json:"columns"`
+	Lines    int    `json:"lines"`
+}
+
+type eventHub struct {
+	mu      sync.Mutex
+	subs    map[chan WindowEvent]struct{}
+	stop    chan struct{}
+	running bool
+	closed  bool
+
+	// returns the id of the window the panel process runs in, or 0 while
+	// it is unknown. Its closing, like losing the socket, ends all
+	// subscriptions.
+	self func() int
+
+	// known visibility, tracked from Show/Hide/ToggleVisibility and
+	// focus loss when the panel hides on focus loss
+	hidden          bool
+	hideOnFocusLoss bool
+}
+
+// Events delivers focus, resize, visibility and close events of the
+// panel's windows until ctx is done or the panel closes.
+//
+// Focus, resize and close come from a kitty watcher when Config.Watcher is
+// set, otherwise from polling ls. kitty does not report visibility, so it
+// is derived from Show, Hide and ToggleVisibility called on k and from
+// focus loss when the panel uses HideOnFocusLoss; visibility changes made
+// by other clients are not seen.
+func (k *Kitty) Events(ctx context.Context) <-chan WindowEvent {
+	h := k.hub()
+	ch := make(chan WindowEvent, 16)
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		close(ch)
+		return ch
+	}
+	h.subs[ch] = struct{}{}
+	if !h.running {
+		h.running = true
+		h.stop = make(chan struct{})
+		go k.runEventSource(h.stop)
+	}
+	h.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		if _, ok := h.subs[ch]; !ok {
+			return
+		}
+		delete(h.subs, ch)
+		close(ch)
+		if len(h.subs) == 0 && h.running {
+			h.running = false
+			close(h.stop)
+		}
+	}()
+
+	return ch
+}
+
+func (k *Kitty) hub() *eventHub {
+	k.eventsOnce.Do(func() {
+		k.events = &eventHub{
+			subs: map[chan WindowEvent]struct{}{},
+			self: k.selfWindowID,
+		}
+	})
+	return k.events
+}
+
+// emit delivers e to all subscribers, dropping it for those that are full.
+func (h *eventHub) emit(e WindowEvent) {
+	// resolved before locking, it may ask the panel for its handshake
+	self := h.self()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.emitLocked(e, self)
+}
+
+func (h *eventHub) emitLocked(e WindowEvent, self int) {
+	if h.closed {
+		return
+	}
+
+	switch e.Kind {
+	case WindowHidden:
+		if h.hidden {
+			return
+		}
+		h.hidden = true
+	case WindowShown:
+		if !h.hidden {
+			return
+		}
+		h.hidden = false
+	}
+
+	for ch := range h.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+
+	if e.Kind == WindowFocusOut && h.hideOnFocusLoss {
+		h.emitLocked(WindowEvent{Kind: WindowHidden, WindowID: e.WindowID}, self)
+	}
+
+	if e.Kind == WindowClosed && (e.WindowID == 0 || (self != 0 && e.WindowID == self)) {
+		h.closed = true
+		for ch := range h.subs {
+			close(ch)
+		}
+		h.subs = nil
+		if h.running {
+			h.running = false
+			close(h.stop)
+		}
+	}
+}
+
+// setConfig records the config of the panel k controls, which seeds the
+// known visibility.
+func (k *Kitty) setConfig(c Config) {
+	k.config = &c
+	if c.RemoteControlPassword != "" {
+		k.SetPassword(c.RemoteControlPassword)
+	}
+
+	h := k.hub()
+	h.mu.Lock()
+	h.hidden = c.StartAsHidden
+	h.hideOnFocusLoss = c.HideOnFocusLoss
+	h.mu.Unlock()
+}
+
+// setVisible records a visibility change made through k.
+func (k *Kitty) setVisible(visible bool) {
+	h := k.hub()
+	if visible {
+		h.emit(WindowEvent{Kind: WindowShown})
+	} else {
+		h.emit(WindowEvent{Kind: WindowHidden})
+	}
+}
+
+func (k *Kitty) toggleVisible() {
+	h := k.hub()
+	h.mu.Lock()
+	hidden := h.hidden
+	h.mu.Unlock()
+
+	k.setVisible(hidden)
+}
+
+func (k *Kitty) runEventSource(stop chan struct{}) {
+	if path := os.Getenv(GetEnvKey("EVENTS")); path != "" {
+		if err := k.listenWatcher(path, stop); err == nil {
+			return
+		}
+	}
+	k.pollEvents(stop)
+}
+
+func (k *Kitty) listenWatcher(path string, stop chan struct{}) error {
+	os.Remove(path)
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-stop
+		ln.Close()
+	}()
+
+	h := k.hub()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			os.Remove(path)
+			return nil
+		}
+
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			var we watcherEvent
+			if json.Unmarshal(scanner.Bytes(), &we) != nil {
+				continue
+			}
+
+			switch we.Event {
+			case "focus":
+				kind := WindowFocusOut
+				if we.Focused {
+					kind = WindowFocusIn
+				}
+				h.emit(WindowEvent{Kind: kind, WindowID: we.WindowID})
+			case "resize":
+				h.emit(WindowEvent{Kind: WindowResized, WindowID: we.WindowID, Columns: we.Columns, Lines: we.Lines})
+			case "close":
+				h.emit(WindowEvent{Kind: WindowClosed, WindowID: we.WindowID})
+			}
+		}
+		conn.Close()
+	}
+}
+
+type windowSnapshot struct {
+	focused        bool
+	columns, lines int
+}
+
+func (k *Kitty) pollEvents(stop chan struct{}) {
+	h := k.hub()
+	ticker := time.NewTicker(EventPollInterval)
+	defer ticker.Stop()
+
+	var last map[int]windowSnapshot
+	for {
+		windows, err := k.Ls(LsRequest{})
+		if err != nil {
+			if isClosedErr(err) {
+				h.emit(WindowEvent{Kind: WindowClosed})
+				return
+			}
+		} else {
+			current := map[int]windowSnapshot{}
+			for _, osw := range windows {
+				for _, tab := range osw.Tabs {
+					for _, w := range tab.Windows {
+						current[w.ID] = windowSnapshot{
+							focused: osw.IsFocused && tab.IsActive && w.IsActive,
+							columns: w.Columns,
+							lines:   w.Lines,
+						}
+					}
+				}
+			}
+
+			if last != nil {
+				diffWindows(h, last, current)
+			}
+			if len(current) == 0 {
+				h.emit(WindowEvent{Kind: WindowClosed})
+				return
+			}
+			last = current
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func diffWindows(h *eventHub, last, current map[int]windowSnapshot) {
+	for id, cur := range current {
+		prev, ok := last[id]
+		if !ok {
+			continue
+		}
+		if prev.focused != cur.focused {
+			kind := WindowFocusOut
+			if cur.focused {
+				kind = WindowFocusIn
+			}
+			h.emit(WindowEvent{Kind: kind, WindowID: id})
+		}
+		if prev.columns != cur.columns || prev.lines != cur.lines {
+			h.emit(WindowEvent{Kind: WindowResized, WindowID: id, Columns: cur.columns, Lines: cur.lines})
+		}
+	}
+
+	for id := range last {
+		if _, ok := current[id]; !ok {
+			h.emit(WindowEvent{Kind: WindowClosed, WindowID: id})
+		}
+	}
+}
+
+// isClosedErr reports whether err means kitty is gone, as opposed to a
+// failed command.
+func isClosedErr(err error) bool {
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ENOENT) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNRESET)
+}
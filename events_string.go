@@ -0,0 +1,29 @@
+// Code generated by "stringer -type=WindowEventKind -linecomment -output events_string.go"; DO NOT EDIT.
+
+package katnip
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[WindowFocusIn-1]
+	_ = x[WindowFocusOut-2]
+	_ = x[WindowResized-3]
+	_ = x[WindowHidden-4]
+	_ = x[WindowShown-5]
+	_ = x[WindowClosed-6]
+}
+
+const _WindowEventKind_name = "focus-infocus-outresizedhiddenshownclosed"
+
+var _WindowEventKind_index = [...]uint8{0, 8, 17, 24, 30, 35, 41}
+
+func (i WindowEventKind) String() string {
+	idx := int(i) - 1
+	if i < 1 || idx >= len(_WindowEventKind_index)-1 {
+		return "WindowEventKind(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _WindowEventKind_name[_WindowEventKind_index[idx]:_WindowEventKind_index[idx+1]]
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip
+
+import "testing"
+
+func TestEventsSelfWindow(t *testing.T) {
+	// the launcher's window on the host, not the panel's
+	t.Setenv("KITTY_WINDOW_ID", "2")
+
+	k := NewKitty("unused")
+	hello := Hello{}
+	k.peer = func() (Hello, bool) { return hello, hello.WindowID > 0 }
+	h := k.hub()
+
+	h.emit(WindowEvent{Kind: WindowClosed, WindowID: 2})
+	if h.closed {
+		t.Fatal("closing another window ended the events")
+	}
+
+	hello.WindowID = 5
+	h.emit(WindowEvent{Kind: WindowClosed, WindowID: 5})
+	if !h.closed {
+		t.Fatal("closing the panel's window did not end the events")
+	}
+}
@@ -2,15 +2,18 @@ module github.com/nekorg/katnip
 
 go 1.24.4
 
-require git.sr.ht/~rockorager/vaxis v0.14.0
+require (
+	git.sr.ht/~rockorager/vaxis v0.14.0
+	github.com/BurntSushi/toml v1.5.0
+	github.com/codelif/shmstream v0.0.0-20250707213419-52bb1dd21b7b
+	golang.org/x/sys v0.33.0
+)
 
 require (
-	github.com/codelif/shmstream v0.0.0-20250707213419-52bb1dd21b7b // indirect
 	github.com/containerd/console v1.0.3 // indirect
 	github.com/mattn/go-runewidth v0.0.14 // indirect
 	github.com/mattn/go-sixel v0.0.5 // indirect
 	github.com/rivo/uniseg v0.4.4 // indirect
 	github.com/soniakeys/quant v1.0.0 // indirect
 	golang.org/x/image v0.9.0 // indirect
-	golang.org/x/sys v0.33.0 // indirect
 )
@@ -0,0 +1,583 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/codelif/shmstream"
+)
+
+// ProtocolVersion is the version of the host/panel handshake and of the
+// messages katnip itself exchanges over shared memory. Host and panel must
+// agree on it.
+const ProtocolVersion = 2
+
+// After the handshake the shared memory stream stays a Channel: bytes
+// written by either side travel as data messages, next to control
+// messages such as stop.
+const (
+	msgHello   = "katnip:hello"
+	msgWelcome = "katnip:welcome"
+	msgReject  = "katnip:reject"
+	msgData    = "katnip:data"
+	msgStop    = "katnip:stop"
+)
+
+// maxDataFrame is the largest payload of a single data message.
+const maxDataFrame = 64 << 10
+
+// Hello is what a panel process announces to its host on startup.
+type Hello struct {
+	Version  int    `json:"version"`
+	Handler  string `json:"handler"`
+	PID      int    `json:"pid"`
+	WindowID int    `json:"window_id"`
+	// KITTY_PUBLIC_KEY of the panel's kitty, for encrypted commands
+	PublicKey string `json:"public_key,omitempty"`
+}
+
+// ErrStreamClosed is returned by the shared memory stream of a panel after
+// Panel.Shutdown.
+var ErrStreamClosed = errors.New("shared memory stream closed")
+
+// HandshakeTimeout is how long a started panel has to say hello before
+// its shared memory stream fails.
+var HandshakeTimeout = 10 * time.Second
+
+var (
+	errNotStarted       = errors.New("panel not started")
+	errPanelExited      = errors.New("handshake: panel exited")
+	errHandshakeTimeout = errors.New("handshake: timed out waiting for the panel")
+)
+
+type welcome struct {
+	Version int             `json:"version"`
+	Args    json.RawMessage `json:"args,omitempty"`
+	// Config.RemoteControlPassword, kept out of the environment
+	Password string `json:"password,omitempty"`
+}
+
+type reject struct {
+	Version int    `json:"version"`
+	Error   string `json:"error"`
+}
+
+// VersionError is returned on both sides when host and panel speak
+// different protocol versions, e.g. when KittyCmd wraps a panel binary
+// built against another katnip.
+type VersionError struct {
+	Host  int
+	Panel int
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("katnip protocol version mismatch: host %d, panel %d", e.Host, e.Panel)
+}
+
+// RejectError is returned in the panel process when the host refused the
+// handshake.
+type RejectError struct {
+	Message string
+}
+
+func (e *RejectError) Error() string {
+	return "rejected by host: " + e.Message
+}
+
+// sendData writes b to ch as data messages.
+func sendData(ch *Channel, b []byte) (int, error) {
+	n := 0
+	for n < len(b) {
+		chunk := b[n:min(len(b), n+maxDataFrame)]
+		if err := ch.SendRaw(msgData, chunk); err != nil {
+			return n, err
+		}
+		n += len(chunk)
+	}
+	return n, nil
+}
+
+// childStream is the panel end of the shared memory stream. After the
+// handshake a goroutine demultiplexes it: data is buffered for Read and
+// stop closes Stopped, after which Read returns io.EOF once the buffered
+// data is consumed.
+type childStream struct {
+	ch *Channel
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	buf  []byte
+	// io.EOF after stop, or why the stream broke
+	err     error
+	stopped chan struct{}
+}
+
+// openChildStream maps the shared memory at path from the panel side.
+func openChildStream(path string) (*childStream, error) {
+	buf, err := shmstream.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open shared memory: %w", err)
+	}
+	writer, err := buf.NewWriter()
+	if err != nil {
+		buf.Close()
+		return nil, fmt.Errorf("failed to create shared memory writer: %w", err)
+	}
+	reader, err := buf.NewReader()
+	if err != nil {
+		buf.Close()
+		return nil, fmt.Errorf("failed to create shared memory reader: %w", err)
+	}
+	return newChildStream(&struct {
+		io.Reader
+		io.Writer
+	}{reader, writer}), nil
+}
+
+func newChildStream(rw io.ReadWriter) *childStream {
+	s := &childStream{
+		ch:      NewChannel(rw, nil),
+		stopped: make(chan struct{}),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// greet performs the panel side of the handshake and returns the welcome
+// sent by the host. Data the host wrote before the panel started is kept,
+// other frames left over from a previous run are skipped.
+func (s *childStream) greet(handler string) (welcome, error) {
+	windowID, _ := strconv.Atoi(os.Getenv("KITTY_WINDOW_ID"))
+
+	hello := Hello{
+		Version:   ProtocolVersion,
+		Handler:   handler,
+		PID:       os.Getpid(),
+		WindowID:  windowID,
+		PublicKey: os.Getenv("KITTY_PUBLIC_KEY"),
+	}
+	if err := s.ch.Send(msgHello, hello); err != nil {
+		return welcome{}, fmt.Errorf("handshake: %w", err)
+	}
+
+	for {
+		msg, err := s.ch.Recv()
+		if err != nil {
+			return welcome{}, fmt.Errorf("handshake: %w", err)
+		}
+
+		switch msg.Type {
+		case msgWelcome:
+			var w welcome
+			if err := msg.Decode(&w); err != nil {
+				return welcome{}, fmt.Errorf("handshake: invalid welcome: %w", err)
+			}
+			if w.Version != ProtocolVersion {
+				return welcome{}, &VersionError{Host: w.Version, Panel: ProtocolVersion}
+			}
+			return w, nil
+		case msgReject:
+			var r reject
+			if err := msg.Decode(&r); err != nil {
+				return welcome{}, fmt.Errorf("handshake: invalid reject: %w", err)
+			}
+			if r.Version != ProtocolVersion {
+				return welcome{}, &VersionError{Host: r.Version, Panel: ProtocolVersion}
+			}
+			return welcome{}, &RejectError{Message: r.Error}
+		case msgData:
+			s.buf = append(s.buf, msg.Body...)
+		}
+	}
+}
+
+// run demultiplexes the stream until the host says stop. Reads from
+// shared memory cannot be interrupted, so it is started once and lives as
+// long as the process.
+func (s *childStream) run() {
+	for {
+		msg, err := s.ch.Recv()
+
+		s.mu.Lock()
+		switch {
+		case err != nil:
+			s.err = err
+		case msg.Type == msgData:
+			s.buf = append(s.buf, msg.Body...)
+		case msg.Type == msgStop:
+			s.err = io.EOF
+			close(s.stopped)
+		}
+		done := s.err != nil
+		s.cond.Broadcast()
+		s.mu.Unlock()
+
+		if done {
+			return
+		}
+	}
+}
+
+// Stopped is closed when the host asks the panel to exit.
+func (s *childStream) Stopped() <-chan struct{} {
+	return s.stopped
+}
+
+func (s *childStream) Read(b []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.buf) == 0 && s.err == nil {
+		s.cond.Wait()
+	}
+	if len(s.buf) == 0 {
+		return 0, s.err
+	}
+	n := copy(b, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+func (s *childStream) Write(b []byte) (int, error) {
+	return sendData(s.ch, b)
+}
+
+// hostStream is the host end of the shared memory stream. It answers the
+// handshake of every run before handing the stream to the user: writes
+// wait until the panel has been welcomed so it never sees user data
+// before the welcome, and reads unwrap the data messages of the panel.
+// A hello is answered whenever it arrives, so a Read blocked across a
+// restart of the panel handles the handshake of the new run.
+type hostStream struct {
+	r  io.Reader
+	w  io.Writer
+	ch *Channel
+
+	rmu sync.Mutex
+	wmu sync.Mutex
+	// data received but not yet read, guarded by rmu
+	buf []byte
+
+	mu      sync.Mutex
+	gen     uint64
+	welcome welcome
+	hello   Hello
+	err     error
+	// closed once the handshake of gen finished, successfully or not
+	done   chan struct{}
+	shaken bool
+	closed bool
+}
+
+func newHostStream(r io.Reader, w io.Writer) *hostStream {
+	s := &hostStream{r: r, w: w}
+	s.ch = NewChannel(&struct {
+		io.Reader
+		io.Writer
+	}{rawReader{s}, w}, nil)
+	return s
+}
+
+// begin prepares for a new run of the panel and answers its handshake in
+// the background with w. It returns the generation of the run.
+func (s *hostStream) begin(w welcome) uint64 {
+	s.mu.Lock()
+	s.gen++
+	gen := s.gen
+	s.welcome = w
+	s.hello = Hello{}
+	s.err = nil
+	s.done = make(chan struct{})
+	s.shaken = false
+	s.mu.Unlock()
+
+	go func() {
+		s.rmu.Lock()
+		defer s.rmu.Unlock()
+		s.shake()
+	}()
+	return gen
+}
+
+// fail ends the handshake of run gen with err unless it already finished.
+func (s *hostStream) fail(gen uint64, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.gen != gen {
+		return
+	}
+	s.finish(Hello{}, err)
+}
+
+// finish records the outcome of the current handshake unless it already
+// finished. Must be called with mu held.
+func (s *hostStream) finish(hello Hello, err error) bool {
+	if s.done == nil || s.shaken {
+		return false
+	}
+	s.hello = hello
+	s.err = err
+	s.shaken = true
+	close(s.done)
+	return true
+}
+
+// result waits for the handshake of the current run.
+func (s *hostStream) result(ctx context.Context) (Hello, error) {
+	s.mu.Lock()
+	done := s.done
+	s.mu.Unlock()
+
+	if done == nil {
+		return Hello{}, errNotStarted
+	}
+
+	// a finished handshake wins over a done ctx
+	select {
+	case <-done:
+	default:
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return Hello{}, ctx.Err()
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hello, s.err
+}
+
+// finished reports whether the handshake of the current run is over.
+func (s *hostStream) finished() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.done == nil || s.shaken, s.err
+}
+
+// shake reads until the handshake of the current run is over, buffering
+// data that arrives meanwhile. Must be called with rmu held.
+func (s *hostStream) shake() error {
+	for {
+		s.mu.Lock()
+		shaken, err := s.shaken || s.done == nil, s.err
+		s.mu.Unlock()
+
+		if shaken {
+			return err
+		}
+		if err := s.receive(); err != nil {
+			s.mu.Lock()
+			s.finish(Hello{}, fmt.Errorf("handshake: %w", err))
+			s.mu.Unlock()
+			return err
+		}
+	}
+}
+
+// receive reads and handles one message. Must be called with rmu held.
+func (s *hostStream) receive() error {
+	msg, err := s.ch.Recv()
+	if err != nil {
+		if s.isClosed() {
+			return ErrStreamClosed
+		}
+		return err
+	}
+
+	switch msg.Type {
+	case msgHello:
+		s.answer(msg)
+	case msgData:
+		s.buf = append(s.buf, msg.Body...)
+	}
+	return nil
+}
+
+// answer replies to a hello and records the result for the current run.
+// A hello arriving after the handshake already failed, e.g. timed out, is
+// rejected so the panel does not run unattended.
+func (s *hostStream) answer(msg Message) {
+	var hello Hello
+	err := msg.Decode(&hello)
+	if err != nil {
+		err = fmt.Errorf("handshake: invalid hello: %w", err)
+	} else if hello.Version != ProtocolVersion {
+		err = &VersionError{Host: ProtocolVersion, Panel: hello.Version}
+	}
+
+	s.mu.Lock()
+	pending := s.done != nil && !s.shaken
+	w, prev := s.welcome, s.err
+	s.mu.Unlock()
+
+	if err == nil && !pending {
+		err = prev
+		if err == nil {
+			err = errors.New("handshake: unexpected hello")
+		}
+		s.send(msgReject, reject{Version: ProtocolVersion, Error: err.Error()})
+		return
+	}
+
+	if err != nil {
+		s.send(msgReject, reject{Version: ProtocolVersion, Error: err.Error()})
+	} else {
+		w.Version = ProtocolVersion
+		if serr := s.send(msgWelcome, w); serr != nil {
+			err = fmt.Errorf("handshake: %w", serr)
+		}
+	}
+
+	s.mu.Lock()
+	s.finish(hello, err)
+	s.mu.Unlock()
+}
+
+// send writes a control message unless the stream is closed.
+func (s *hostStream) send(typ string, v any) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	if s.isClosed() {
+		return ErrStreamClosed
+	}
+	return s.ch.Send(typ, v)
+}
+
+// stop asks the handler of the current run to exit. It reports false if
+// the run has not been welcomed, in which case the panel cannot be told.
+// The message is sent in the background so that a panel which stopped
+// reading cannot block the caller.
+func (s *hostStream) stop() bool {
+	if done, err := s.finished(); !done || err != nil {
+		return false
+	}
+	if _, err := s.result(context.Background()); err != nil {
+		return false
+	}
+	go s.send(msgStop, nil)
+	return true
+}
+
+func (s *hostStream) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.closed
+}
+
+// close stops all use of the stream so its memory can be unmapped. A Read
+// blocked on the panel is woken by writing a byte through a second mapping
+// of path. It reports false if a Write is still blocked on a full buffer,
+// in which case the memory must stay mapped.
+func (s *hostStream) close(path string) bool {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return true
+	}
+	s.closed = true
+	s.finish(Hello{}, ErrStreamClosed)
+	s.mu.Unlock()
+
+	if !s.rmu.TryLock() {
+		if peer, err := shmstream.Open(path); err == nil {
+			if w, err := peer.NewWriter(); err == nil {
+				w.Write([]byte{0})
+			}
+			peer.Close()
+		}
+		s.rmu.Lock()
+	}
+	s.rmu.Unlock()
+
+	if !s.wmu.TryLock() {
+		return false
+	}
+	s.wmu.Unlock()
+	return true
+}
+
+func (s *hostStream) Read(b []byte) (int, error) {
+	s.rmu.Lock()
+	defer s.rmu.Unlock()
+
+	for {
+		if s.isClosed() {
+			return 0, ErrStreamClosed
+		}
+		if len(s.buf) > 0 {
+			n := copy(b, s.buf)
+			s.buf = s.buf[n:]
+			return n, nil
+		}
+		if err := s.shake(); err != nil {
+			return 0, err
+		}
+		if err := s.receive(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (s *hostStream) Write(b []byte) (int, error) {
+	return s.writeContext(context.Background(), b)
+}
+
+// writeContext is Write giving up on the handshake once ctx is done.
+func (s *hostStream) writeContext(ctx context.Context, b []byte) (int, error) {
+	if _, err := s.result(ctx); err != nil && !errors.Is(err, errNotStarted) {
+		return 0, err
+	}
+
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	if s.isClosed() {
+		return 0, ErrStreamClosed
+	}
+	return sendData(s.ch, b)
+}
+
+// rawReader reads the stream, discarding whatever was read once the stream
+// is closed.
+type rawReader struct {
+	s *hostStream
+}
+
+func (r rawReader) Read(b []byte) (int, error) {
+	n, err := r.s.r.Read(b)
+	if r.s.isClosed() {
+		return 0, ErrStreamClosed
+	}
+	return n, err
+}
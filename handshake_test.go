@@ -0,0 +1,227 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/codelif/shmstream"
+)
+
+// streamPair connects a host and a panel end over shared memory, as a
+// Panel and runPanel do. The panel end is left mapped since its reader
+// may still be blocked when the test ends.
+func streamPair(t *testing.T) (*hostStream, *childStream) {
+	t.Helper()
+
+	buf, err := shmstream.New(shmstream.Config{Bidirectional: true})
+	if err != nil {
+		t.Skip("no shared memory:", err)
+	}
+	reader, _ := buf.NewReader()
+	writer, _ := buf.NewWriter()
+	host := newHostStream(reader, writer)
+	t.Cleanup(func() {
+		if host.close(buf.Path()) {
+			buf.Close()
+		}
+	})
+
+	child, err := openChildStream(buf.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return host, child
+}
+
+func TestStreamStop(t *testing.T) {
+	host, child := streamPair(t)
+
+	// written before the panel started, kept for it
+	if _, err := host.Write([]byte("early ")); err != nil {
+		t.Fatal(err)
+	}
+	host.begin(welcome{Args: []byte(`{"feed":"x"}`)})
+
+	w, err := child.greet("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(w.Args) != `{"feed":"x"}` {
+		t.Errorf("args = %s", w.Args)
+	}
+	go child.run()
+
+	hello, err := host.result(t.Context())
+	if err != nil || hello.Handler != "test" || hello.PID != os.Getpid() {
+		t.Fatalf("hello = %+v, %v", hello, err)
+	}
+
+	// larger than a single data frame, both ways
+	big := bytes.Repeat([]byte("0123456789"), maxDataFrame/4)
+	go child.Write(big)
+	got := make([]byte, len(big))
+	if _, err := io.ReadFull(host, got); err != nil || !bytes.Equal(got, big) {
+		t.Fatalf("host read %d bytes, %v", len(got), err)
+	}
+
+	if _, err := host.Write(big); err != nil {
+		t.Fatal(err)
+	}
+	if !host.stop() {
+		t.Fatal("stop() = false after a handshake")
+	}
+	select {
+	case <-child.Stopped():
+	case <-time.After(5 * time.Second):
+		t.Fatal("stop not received")
+	}
+
+	// everything sent before stop is still read, then EOF
+	got, err = io.ReadAll(child)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := append([]byte("early "), big...); !bytes.Equal(got, want) {
+		t.Errorf("panel read %d bytes, want %d", len(got), len(want))
+	}
+}
+
+func TestStreamStopCancelsContext(t *testing.T) {
+	host, child := streamPair(t)
+
+	host.begin(welcome{})
+	if _, err := child.greet("test"); err != nil {
+		t.Fatal(err)
+	}
+	go child.run()
+	if _, err := host.result(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	var cause error
+	handler := PanelContextFunc(func(ctx context.Context, in *Instance) int {
+		host.stop()
+		<-ctx.Done()
+		cause = context.Cause(ctx)
+		return 3
+	})
+	if code := runContext(handler, &Instance{RW: child}, child.Stopped()); code != 3 {
+		t.Errorf("exit code = %d, want 3", code)
+	}
+	if !errors.Is(cause, ErrShutdown) {
+		t.Errorf("cause = %v, want %v", cause, ErrShutdown)
+	}
+}
+
+func TestStreamStopBeforeHandshake(t *testing.T) {
+	host, _ := streamPair(t)
+	if host.stop() {
+		t.Error("stop() = true before the panel started")
+	}
+	gen := host.begin(welcome{})
+	if host.stop() {
+		t.Error("stop() = true before the hello")
+	}
+	host.fail(gen, errPanelExited)
+	if host.stop() {
+		t.Error("stop() = true after a failed handshake")
+	}
+}
+
+// writeWithin writes to the panel's stream, failing t if the write is
+// still blocked after d.
+func writeWithin(t *testing.T, p *Panel, d time.Duration) error {
+	t.Helper()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.ReadWriter().Write([]byte("data"))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(d):
+		t.Fatal("write blocked")
+		return nil
+	}
+}
+
+func TestPanelExitsWithoutHello(t *testing.T) {
+	p := NewPanel("no-hello", Config{KittyCmd: "true"})
+	defer p.Shutdown(t.Context())
+	if p.ReadWriter() == nil {
+		t.Skip("no shared memory")
+	}
+
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeWithin(t, p, 5*time.Second); !errors.Is(err, errPanelExited) {
+		t.Errorf("Write error = %v, want %v", err, errPanelExited)
+	}
+	if _, err := p.Hello(t.Context()); !errors.Is(err, errPanelExited) {
+		t.Errorf("Hello error = %v, want %v", err, errPanelExited)
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHandshakeTimeout(t *testing.T) {
+	defer func(d time.Duration) { HandshakeTimeout = d }(HandshakeTimeout)
+	HandshakeTimeout = 100 * time.Millisecond
+
+	// stays up without ever saying hello
+	script := filepath.Join(t.TempDir(), "silent-kitty")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nexec sleep 30\n"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPanel("silent", Config{KittyCmd: script})
+	if p.ReadWriter() == nil {
+		t.Skip("no shared memory")
+	}
+
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeWithin(t, p, 5*time.Second); !errors.Is(err, errHandshakeTimeout) {
+		t.Errorf("Write error = %v, want %v", err, errHandshakeTimeout)
+	}
+	if err := p.WaitReady(t.Context()); !errors.Is(err, errHandshakeTimeout) {
+		t.Errorf("WaitReady error = %v, want %v", err, errHandshakeTimeout)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+	defer cancel()
+	p.Shutdown(ctx)
+	if p.running() {
+		t.Error("panel still running after Shutdown")
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// Instance describes the running panel to its handler.
+type Instance struct {
+	// name the handler was registered under
+	Name string
+	// id of the kitty window the handler runs in
+	WindowID int
+	Kitty    *Kitty
+	// shared memory stream to the host, nil if there is none
+	RW io.ReadWriter
+	// Edge, Layer, Size, Margins, StartAsHidden and HideOnFocusLoss of
+	// the Config the host started the panel with; other fields are zero
+	Config Config
+
+	args json.RawMessage
+
+	mu      sync.Mutex
+	channel *Channel
+	rpc     *RPC
+}
+
+// Args decodes the value the host supplied in Config.Args or with
+// Panel.SetArgs into v. Without args v is left untouched.
+func (in *Instance) Args(v any) error {
+	if len(in.args) == 0 {
+		return nil
+	}
+	return json.Unmarshal(in.args, v)
+}
+
+// RawArgs returns the JSON encoded args, or nil.
+func (in *Instance) RawArgs() json.RawMessage {
+	return in.args
+}
+
+// Channel returns a message channel to the host using JSONCodec, or nil if
+// there is no shared memory.
+func (in *Instance) Channel() *Channel {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if in.RW == nil {
+		return nil
+	}
+	if in.channel == nil {
+		in.channel = NewChannel(in.RW, nil)
+	}
+	return in.channel
+}
+
+// RPC returns the RPC endpoint on Channel, or nil if there is no shared
+// memory.
+func (in *Instance) RPC() *RPC {
+	ch := in.Channel()
+	if ch == nil {
+		return nil
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if in.rpc == nil {
+		in.rpc = NewRPC(ch)
+	}
+	return in.rpc
+}
+
+// InstanceHandler is a PanelHandler that is given the whole Instance.
+// runPanel prefers RunInstance over Run, see also PanelHandlerContext.
+type InstanceHandler interface {
+	PanelHandler
+	RunInstance(in *Instance) int
+}
+
+type InstanceFunc func(in *Instance) int
+
+func (f InstanceFunc) Run(k *Kitty, rw io.ReadWriter) int {
+	return f(&Instance{Kitty: k, RW: rw})
+}
+
+func (f InstanceFunc) RunInstance(in *Instance) int {
+	return f(in)
+}
+
+func RegisterInstanceFunc(name string, panel InstanceFunc) {
+	Register(name, panel)
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package base85 implements the base85 encoding of Python's
+// base64.b85encode, which kitty uses for keys and encrypted payloads.
+package base85
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+const alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz!#$%&()*+-;<=>?@^_`{|}~"
+
+// Encode returns the base85 encoding of b, without padding.
+func Encode(b []byte) string {
+	padding := (4 - len(b)%4) % 4
+	buf := append(append([]byte{}, b...), make([]byte, padding)...)
+
+	out := make([]byte, 0, len(buf)/4*5)
+	for i := 0; i < len(buf); i += 4 {
+		v := binary.BigEndian.Uint32(buf[i : i+4])
+		var chunk [5]byte
+		for j := 4; j >= 0; j-- {
+			chunk[j] = alphabet[v%85]
+			v /= 85
+		}
+		out = append(out, chunk[:]...)
+	}
+	return string(out[:len(out)-padding])
+}
+
+// Decode returns the bytes represented by the base85 string s.
+func Decode(s string) ([]byte, error) {
+	padding := (5 - len(s)%5) % 5
+	s += strings.Repeat("~", padding)
+
+	out := make([]byte, 0, len(s)/5*4)
+	for i := 0; i < len(s); i += 5 {
+		var v uint64
+		for _, c := range []byte(s[i : i+5]) {
+			d := strings.IndexByte(alphabet, c)
+			if d < 0 {
+				return nil, fmt.Errorf("invalid base85 character %q", c)
+			}
+			v = v*85 + uint64(d)
+		}
+		if v > 0xffffffff {
+			return nil, fmt.Errorf("base85 overflow at %d", i)
+		}
+		out = binary.BigEndian.AppendUint32(out, uint32(v))
+	}
+	return out[:len(out)-padding], nil
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package base85
+
+import (
+	"bytes"
+	"testing"
+)
+
+// expected values from Python's base64.b85encode
+var vectors = []struct {
+	raw     string
+	encoded string
+}{
+	{"", ""},
+	{"\x00", "00"},
+	{"a", "VE"},
+	{"ab", "VPX"},
+	{"abc", "VPaz"},
+	{"abcd", "VPa!s"},
+	{"hello world", "Xk~0{Zy<MXa%^M"},
+	{"\xff\xff\xff\xff", "|NsC0"},
+	{
+		"\x00\x01\x02\x03\x04\x05\x06\x07\x08\t\n\x0b\x0c\r\x0e\x0f" +
+			"\x10\x11\x12\x13\x14\x15\x16\x17\x18\x19\x1a\x1b\x1c\x1d\x1e\x1f",
+		"009C61O)~M2nh-c3=Iws5D^j+6crX17#SKH9337X",
+	},
+}
+
+func TestEncode(t *testing.T) {
+	for _, v := range vectors {
+		if got := Encode([]byte(v.raw)); got != v.encoded {
+			t.Errorf("Encode(%q) = %q, want %q", v.raw, got, v.encoded)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	for _, v := range vectors {
+		got, err := Decode(v.encoded)
+		if err != nil || !bytes.Equal(got, []byte(v.raw)) {
+			t.Errorf("Decode(%q) = %q, %v, want %q", v.encoded, got, err, v.raw)
+		}
+	}
+
+	for _, s := range []string{"VP\"", "VPa s", "~~~~~"} {
+		if got, err := Decode(s); err == nil {
+			t.Errorf("Decode(%q) = %q, want error", s, got)
+		}
+	}
+}
@@ -20,11 +20,11 @@
 package katnip
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
-
-	"github.com/codelif/shmstream"
+	"strconv"
 )
 
 var registry = map[string]PanelHandler{}
@@ -32,8 +32,9 @@ var registry = map[string]PanelHandler{}
 func Register(name string, panel PanelHandler) {
 	instance := os.Getenv(GetEnvKey("INSTANCE"))
 	if instance != "" && instance == name {
-		panelExitCode, err := runPanel(panel)
+		panelExitCode, err := runPanel(name, panel)
 		if err != nil {
+			fmt.Fprintf(os.Stderr, "katnip: panel %q: %v\n", name, err)
 			os.Exit(1)
 		}
 		os.Exit(panelExitCode)
@@ -46,37 +47,72 @@ func RegisterFunc(name string, panel PanelFunc) {
 	Register(name, panel)
 }
 
-func runPanel(panel PanelHandler) (int, error) {
+func runPanel(name string, panel PanelHandler) (int, error) {
 	socketPath := os.Getenv(GetEnvKey("SOCKET"))
 	if socketPath == "" {
 		return -1, fmt.Errorf("Kitty socket path not given")
 	}
 
+	in := &Instance{Name: name}
+	in.WindowID, _ = strconv.Atoi(os.Getenv("KITTY_WINDOW_ID"))
+
 	shmPath := os.Getenv(GetEnvKey("SHM_PATH"))
 	var shmIo io.ReadWriter
+	var stopped <-chan struct{}
+	var password string
 	if shmPath != "" {
-		shmBuf, err := shmstream.Open(shmPath)
+		stream, err := openChildStream(shmPath)
 		if err != nil {
-			return -1, fmt.Errorf("failed to open shared memory: %w", err)
+			return -1, err
 		}
 
-		defer shmBuf.Close()
-
-		writer, err := shmBuf.NewWriter()
+		w, err := stream.greet(name)
 		if err != nil {
-			return -1, fmt.Errorf("failed to create shared memory writer: %w", err)
+			return -1, err
 		}
-		reader, err := shmBuf.NewReader()
-		if err != nil {
-			return -1, fmt.Errorf("failed to create shared memory reader: %w", err)
+		// the mapping stays until the process exits, see childStream.run
+		go stream.run()
+
+		shmIo = stream
+		stopped = stream.Stopped()
+		in.args = w.Args
+		password = w.Password
+	} else {
+		// without shared memory the host falls back to the environment
+		if args := os.Getenv(GetEnvKey("ARGS")); args != "" {
+			in.args = json.RawMessage(args)
 		}
-		shmIo = &struct {
-			io.Reader
-			io.Writer
-		}{reader, writer}
+		password = os.Getenv(GetEnvKey("PASSWORD"))
 	}
 	k := NewKitty(socketPath)
+	k.inPanel = true
+	k.windowID, _ = strconv.Atoi(os.Getenv("KITTY_WINDOW_ID"))
+	if key := os.Getenv("KITTY_PUBLIC_KEY"); key != "" {
+		k.SetPublicKey(key)
+	}
+	k.SetPassword(password)
+	if c := os.Getenv(GetEnvKey("CONFIG")); c != "" {
+		var pc panelConfig
+		if err := json.Unmarshal([]byte(c), &pc); err == nil {
+			in.Config = pc.config()
+			k.setConfig(in.Config)
+		}
+	}
 
+	if readyPath := os.Getenv(GetEnvKey("READY")); readyPath != "" {
+		if f, err := os.Create(readyPath); err == nil {
+			f.Close()
+		}
+	}
+
+	in.Kitty = k
+	in.RW = shmIo
+	switch h := panel.(type) {
+	case PanelHandlerContext:
+		return runContext(h, in, stopped), nil
+	case InstanceHandler:
+		return h.RunInstance(in), nil
+	}
 	return panel.Run(k, shmIo), nil
 }
 
@@ -0,0 +1,511 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package katniptest provides an in-process fake of kitty's remote-control
+// socket, for testing code built on katnip without a running kitty.
+package katniptest
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/nekorg/katnip"
+	"github.com/nekorg/katnip/internal/base85"
+)
+
+var (
+	msgPrefix = []byte("\x1bP@kitty-cmd")
+	msgSuffix = []byte("\x1b\\")
+)
+
+// Command is a remote-control command as received by the Server.
+type Command struct {
+	Name          string          `json:"cmd"`
+	Version       [3]uint64       `json:"version"`
+	NoResponse    bool            `json:"no_response,omitempty"`
+	KittyWindowID uint64          `json:"kitty_window_id,omitempty"`
+	Payload       json.RawMessage `json:"payload,omitempty"`
+	Password      string          `json:"password,omitempty"`
+	Timestamp     int64           `json:"timestamp,omitempty"`
+
+	// whether the command arrived as an encrypted_cmd
+	Encrypted bool `json:"-"`
+	// the undecoded frame, without prefix and suffix
+	Raw []byte `json:"-"`
+}
+
+// Decode unmarshals the command payload into v.
+func (c Command) Decode(v any) error {
+	if len(c.Payload) == 0 {
+		return nil
+	}
+	return json.Unmarshal(c.Payload, v)
+}
+
+// Response describes how the Server answers a single command.
+// Use OK, Error, Malformed, Disconnect and Slow to build one.
+type Response struct {
+	data       any
+	err        string
+	isErr      bool
+	raw        []byte
+	disconnect bool
+	delay      time.Duration
+}
+
+// OK answers with {"ok": true, "data": data}. data is omitted when nil.
+func OK(data any) Response {
+	return Response{data: data}
+}
+
+// Error answers with {"ok": false, "error": msg}.
+func Error(msg string) Response {
+	return Response{err: msg, isErr: true}
+}
+
+// Malformed writes raw as the frame body verbatim.
+func Malformed(raw string) Response {
+	return Response{raw: []byte(raw)}
+}
+
+// Disconnect closes the connection instead of answering.
+func Disconnect() Response {
+	return Response{disconnect: true}
+}
+
+// Slow delays r by d.
+func Slow(d time.Duration, r Response) Response {
+	r.delay = d
+	return r
+}
+
+// Handler computes the response to a command.
+type Handler func(cmd Command) Response
+
+// Server is a fake kitty listening on a unix socket.
+type Server struct {
+	dir  string
+	path string
+	ln   net.Listener
+
+	mu       sync.Mutex
+	commands []Command
+	queued   map[string][]Response
+	handlers map[string]Handler
+	conns    map[net.Conn]struct{}
+	notify   chan struct{}
+
+	password string
+
+	wg     sync.WaitGroup
+	closed bool
+}
+
+// testKey is the fixed X25519 key of every Server, so encrypted commands
+// can be decrypted without a kitty.
+var testKey = func() *ecdh.PrivateKey {
+	seed := make([]byte, 32)
+	for i := range seed {
+		seed[i] = byte(i + 1)
+	}
+	key, err := ecdh.X25519().NewPrivateKey(seed)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+// PublicKey is the public key of every Server, in the form kitty exports
+// in KITTY_PUBLIC_KEY.
+var PublicKey = "1:" + base85.Encode(testKey.PublicKey().Bytes())
+
+// NewServer starts a Server on a socket in a fresh temporary directory.
+// The caller must call Close when done.
+func NewServer() (*Server, error) {
+	dir, err := os.MkdirTemp("", "katniptest-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+
+	path := filepath.Join(dir, "kitty.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+
+	s := &Server{
+		dir:      dir,
+		path:     path,
+		ln:       ln,
+		queued:   map[string][]Response{},
+		handlers: map[string]Handler{},
+		conns:    map[net.Conn]struct{}{},
+		notify:   make(chan struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+
+	return s, nil
+}
+
+// SocketPath returns the path of the unix socket.
+func (s *Server) SocketPath() string {
+	return s.path
+}
+
+// Kitty returns a new client connected to s, knowing its PublicKey.
+func (s *Server) Kitty() *katnip.Kitty {
+	k := katnip.NewKitty(s.path)
+	k.SetPublicKey(PublicKey)
+	return k
+}
+
+// Env returns the environment a panel process expects, pointing at s.
+func (s *Server) Env() []string {
+	return []string{
+		katnip.GetEnvPair("SOCKET", s.path),
+		"KITTY_PUBLIC_KEY=" + PublicKey,
+	}
+}
+
+// SetPassword makes s answer commands whose password differs from
+// password with an error, like kitty with remote_control_password set.
+// Commands must then be encrypted. An empty password accepts any command.
+func (s *Server) SetPassword(password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.password = password
+}
+
+// Handle sets the handler for cmd. Queued responses take precedence.
+func (s *Server) Handle(cmd string, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[cmd] = h
+}
+
+// Respond queues responses for cmd, consumed one per command in order.
+// Once the queue is drained, the handler for cmd (or OK(nil)) is used.
+func (s *Server) Respond(cmd string, r ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queued[cmd] = append(s.queued[cmd], r...)
+}
+
+// Commands returns a copy of all commands received so far.
+func (s *Server) Commands() []Command {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Command(nil), s.commands...)
+}
+
+// Reset forgets received commands, queued responses and handlers.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.commands = nil
+	s.queued = map[string][]Response{}
+	s.handlers = map[string]Handler{}
+}
+
+// WaitFor blocks until n commands have been received or timeout expires,
+// and returns the commands received so far.
+func (s *Server) WaitFor(n int, timeout time.Duration) ([]Command, error) {
+	deadline := time.After(timeout)
+	for {
+		s.mu.Lock()
+		if len(s.commands) >= n {
+			cmds := append([]Command(nil), s.commands...)
+			s.mu.Unlock()
+			return cmds, nil
+		}
+		notify := s.notify
+		s.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-deadline:
+			return s.Commands(), fmt.Errorf("timed out waiting for %d commands", n)
+		}
+	}
+}
+
+// DropConnections closes all open client connections, leaving the
+// listener running.
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for c := range s.conns {
+		c.Close()
+	}
+}
+
+// Close stops the server, closes all connections and removes the socket.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+
+	err := s.ln.Close()
+	s.wg.Wait()
+	os.RemoveAll(s.dir)
+
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	reader := bufio.NewReader(conn)
+	for {
+		frame, err := readFrame(reader)
+		if err != nil {
+			return
+		}
+
+		cmd, err := decodeCommand(frame)
+		if err != nil {
+			if writeFrame(conn, Error(err.Error()).encode()) != nil {
+				return
+			}
+			continue
+		}
+
+		resp := s.record(cmd)
+		if resp.delay > 0 {
+			time.Sleep(resp.delay)
+		}
+		if resp.disconnect {
+			return
+		}
+		if cmd.NoResponse {
+			continue
+		}
+		if err := writeFrame(conn, resp.encode()); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) record(cmd Command) Response {
+	s.mu.Lock()
+	s.commands = append(s.commands, cmd)
+	close(s.notify)
+	s.notify = make(chan struct{})
+
+	if s.password != "" && (!cmd.Encrypted || cmd.Password != s.password) {
+		s.mu.Unlock()
+		return Error("invalid password")
+	}
+
+	if q := s.queued[cmd.Name]; len(q) > 0 {
+		s.queued[cmd.Name] = q[1:]
+		s.mu.Unlock()
+		return q[0]
+	}
+	h := s.handlers[cmd.Name]
+	s.mu.Unlock()
+
+	if h != nil {
+		return h(cmd)
+	}
+	return OK(nil)
+}
+
+func decodeCommand(frame []byte) (Command, error) {
+	var env struct {
+		Encrypted string `json:"encrypted"`
+		IV        string `json:"iv"`
+		Tag       string `json:"tag"`
+		Pubkey    string `json:"pubkey"`
+	}
+	if err := json.Unmarshal(frame, &env); err != nil {
+		return Command{}, errors.New("malformed command")
+	}
+
+	body := frame
+	if env.Encrypted != "" {
+		var err error
+		if body, err = decrypt(env.Encrypted, env.IV, env.Tag, env.Pubkey); err != nil {
+			return Command{}, fmt.Errorf("failed to decrypt command: %w", err)
+		}
+	}
+
+	var cmd Command
+	if err := json.Unmarshal(body, &cmd); err != nil {
+		return Command{}, errors.New("malformed command")
+	}
+	cmd.Encrypted = env.Encrypted != ""
+	cmd.Raw = frame
+	return cmd, nil
+}
+
+func decrypt(encrypted, iv, tag, pubkey string) ([]byte, error) {
+	var parts [4][]byte
+	for i, s := range []string{encrypted, iv, tag, pubkey} {
+		b, err := base85.Decode(s)
+		if err != nil {
+			return nil, err
+		}
+		parts[i] = b
+	}
+
+	pub, err := ecdh.X25519().NewPublicKey(parts[3])
+	if err != nil {
+		return nil, err
+	}
+	secret, err := testKey.ECDH(pub)
+	if err != nil {
+		return nil, err
+	}
+	key := sha256.Sum256(secret)
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts[1]) != gcm.NonceSize() {
+		return nil, errors.New("invalid iv")
+	}
+	return gcm.Open(nil, parts[1], append(parts[0], parts[2]...), nil)
+}
+
+func (r Response) encode() []byte {
+	if r.raw != nil {
+		return r.raw
+	}
+
+	resp := map[string]any{"ok": !r.isErr}
+	if r.isErr {
+		resp["error"] = r.err
+	} else if r.data != nil {
+		resp["data"] = r.data
+	}
+
+	b, err := json.Marshal(resp)
+	if err != nil {
+		b, _ = json.Marshal(map[string]any{
+			"ok":    false,
+			"error": fmt.Sprintf("katniptest: failed to encode response: %v", err),
+		})
+	}
+	return b
+}
+
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	prefix := make([]byte, len(msgPrefix))
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(prefix, msgPrefix) {
+		return nil, errors.New("invalid frame prefix")
+	}
+
+	var buf bytes.Buffer
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == msgSuffix[0] {
+			next, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			if next == msgSuffix[1] {
+				return buf.Bytes(), nil
+			}
+			buf.WriteByte(b)
+			buf.WriteByte(next)
+			continue
+		}
+		buf.WriteByte(b)
+	}
+}
+
+func writeFrame(w io.Writer, body []byte) error {
+	frame := make([]byte, 0, len(msgPrefix)+len(body)+len(msgSuffix))
+	frame = append(frame, msgPrefix...)
+	frame = append(frame, body...)
+	frame = append(frame, msgSuffix...)
+
+	_, err := w.Write(frame)
+	return err
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katniptest
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newServer(t *testing.T) *Server {
+	t.Helper()
+
+	s, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func dial(t *testing.T, s *Server) (net.Conn, *bufio.Reader) {
+	t.Helper()
+
+	conn, err := net.Dial("unix", s.SocketPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return conn, bufio.NewReader(conn)
+}
+
+func TestFraming(t *testing.T) {
+	s := newServer(t)
+	s.Respond("ls", OK([]int{1}))
+	conn, r := dial(t, s)
+
+	// the terminator may be split across writes and ESC may appear alone
+	body := `{"cmd":"ls","version":[0,42,0],"payload":{"s":"a\u001bb"}}`
+	frame := string(msgPrefix) + body + string(msgSuffix)
+	for _, part := range []string{frame[:5], frame[5 : len(frame)-1], frame[len(frame)-1:]} {
+		if _, err := conn.Write([]byte(part)); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	resp, err := readFrame(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(resp), `{"data":[1],"ok":true}`; got != want {
+		t.Errorf("response = %s, want %s", got, want)
+	}
+
+	cmds := s.Commands()
+	if len(cmds) != 1 {
+		t.Fatalf("got %d commands, want 1", len(cmds))
+	}
+	if cmds[0].Name != "ls" || cmds[0].Version != [3]uint64{0, 42, 0} {
+		t.Errorf("command = %+v", cmds[0])
+	}
+	if string(cmds[0].Raw) != body {
+		t.Errorf("raw = %q, want %q", cmds[0].Raw, body)
+	}
+}
+
+func TestNoResponse(t *testing.T) {
+	s := newServer(t)
+	s.Respond("set-font-size", Error("not answered"))
+	k := s.Kitty()
+	defer k.Close()
+
+	if err := k.DispatchAsync("set-font-size", map[string]int{"size": 12}); err != nil {
+		t.Fatal(err)
+	}
+	// an answer to the async command would be read as the reply to ls
+	if _, err := k.Command("ls", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	cmds := s.Commands()
+	if len(cmds) != 2 || !cmds[0].NoResponse || cmds[1].NoResponse {
+		t.Fatalf("commands = %+v", cmds)
+	}
+}
+
+func TestErrorReplies(t *testing.T) {
+	s := newServer(t)
+	s.Respond("ls", Error("no such window"), Malformed(`{"ok": false}`))
+	s.Handle("ls", func(Command) Response { return Error("from handler") })
+	k := s.Kitty()
+	defer k.Close()
+
+	for _, want := range []string{
+		"kitty error: no such window",
+		"field 'error' is missing",
+		"kitty error: from handler",
+	} {
+		_, err := k.Command("ls", nil)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %v, want %q", err, want)
+		}
+	}
+
+	conn, r := dial(t, s)
+	if err := writeFrame(conn, []byte("not json")); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := readFrame(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(resp), `{"error":"malformed command","ok":false}`; got != want {
+		t.Errorf("response = %s, want %s", got, want)
+	}
+}
+
+func TestEncryptedCommand(t *testing.T) {
+	s := newServer(t)
+	s.SetPassword("secret")
+	k := s.Kitty()
+	defer k.Close()
+
+	if _, err := k.Command("ls", nil); err == nil || !strings.Contains(err.Error(), "invalid password") {
+		t.Errorf("plain command: error = %v, want invalid password", err)
+	}
+
+	k.SetPassword("wrong")
+	if _, err := k.Command("ls", nil); err == nil || !strings.Contains(err.Error(), "invalid password") {
+		t.Errorf("wrong password: error = %v, want invalid password", err)
+	}
+
+	k.SetPassword("secret")
+	if err := k.Dispatch("set-font-size", map[string]int{"size": 12}); err != nil {
+		t.Fatal(err)
+	}
+
+	cmds := s.Commands()
+	cmd := cmds[len(cmds)-1]
+	if !cmd.Encrypted || cmd.Name != "set-font-size" || cmd.Password != "secret" || cmd.Timestamp == 0 {
+		t.Fatalf("command = %+v", cmd)
+	}
+	if strings.Contains(string(cmd.Raw), "secret") {
+		t.Errorf("password sent in the clear: %s", cmd.Raw)
+	}
+	var payload struct{ Size int }
+	if err := cmd.Decode(&payload); err != nil || payload.Size != 12 {
+		t.Errorf("payload = %+v, %v", payload, err)
+	}
+}
+
+func TestTypedCommandContext(t *testing.T) {
+	s := newServer(t)
+	s.Respond("get-text", Slow(time.Second, OK("late")))
+	k := s.Kitty()
+	defer k.Close()
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := k.WindowTarget(1).GetTextContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want deadline exceeded", err)
+	}
+}
@@ -22,11 +22,16 @@ package katnip
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"crypto/ecdh"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"sync"
+	"syscall"
+	"time"
 )
 
 const esc byte = 0x1b
@@ -43,6 +48,15 @@ type kittySockMsg struct {
 	NoResponse    bool            `json:"no_response,omitempty"`
 	KittyWindowId uint64          `json:"kitty_window_id,omitempty"`
 	Payload       json.RawMessage `json:"payload,omitempty"`
+	// only sent encrypted, see encrypt
+	Password  string `json:"password,omitempty"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+}
+
+type kittyResponse struct {
+	Ok    *bool           `json:"ok"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	Error *string         `json:"error,omitempty"`
 }
 
 type Kitty struct {
@@ -51,34 +65,85 @@ type Kitty struct {
 	reader     *bufio.Reader
 	mu         sync.Mutex
 	connected  bool
+
+	eventsOnce sync.Once
+	events     *eventHub
+
+	// config of the panel k controls, if known
+	config *Config
+	// set when k is the panel's own client, created by runPanel
+	inPanel bool
+	// last absolute size set by SetFontSize
+	fontSize float64
+	// how long to wait for the socket to appear when connecting
+	dialWait time.Duration
+
+	// remote_control_password and the key commands are encrypted for
+	password  string
+	publicKey *ecdh.PublicKey
+	// window commands are sent from, see SetWindowID
+	windowID int
+	// handshake of the panel k controls, if any, set by Panel.Kitty
+	peer func() (Hello, bool)
+
+	retry RetryPolicy
+	// set when a connection was established after an earlier one was lost
+	reconnected    bool
+	everConnected  bool
+	reconnectHooks []func()
 }
 
 func NewKitty(socketPath string) *Kitty {
-	return &Kitty{socketPath: socketPath}
+	return &Kitty{socketPath: socketPath, retry: DefaultRetryPolicy}
 }
 
-func (k *Kitty) connect() error {
+func (k *Kitty) connect(ctx context.Context) error {
 	if k.connected {
 		return nil
 	}
-	conn, err := net.Dial("unix", k.socketPath)
+	var d net.Dialer
+	deadline := time.Now().Add(k.dialWait)
+	conn, err := d.DialContext(ctx, "unix", k.socketPath)
+	for err != nil && socketPending(err) && time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(socketPollInterval):
+		}
+		conn, err = d.DialContext(ctx, "unix", k.socketPath)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to connect to kitty socket: %w", err)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &connError{err: fmt.Errorf("failed to connect to kitty socket: %w", err)}
 	}
 
 	k.conn = conn
 	k.reader = bufio.NewReader(conn)
 	k.connected = true
+	if k.everConnected {
+		k.reconnected = true
+	}
+	k.everConnected = true
 
 	return nil
 }
 
-func (k *Kitty) ensureConnected() error {
+const socketPollInterval = 50 * time.Millisecond
+
+// socketPending reports whether a dial error means kitty has not created
+// or started listening on the socket yet.
+func socketPending(err error) bool {
+	return errors.Is(err, syscall.ENOENT) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+func (k *Kitty) ensureConnected(ctx context.Context) error {
 	if k.connected && k.conn != nil {
 		return nil
 	}
 
-	return k.connect()
+	return k.connect(ctx)
 }
 
 func (k *Kitty) Close() error {
@@ -93,10 +158,23 @@ func (k *Kitty) Close() error {
 	k.conn = nil
 	k.reader = nil
 	k.connected = false
+	// an explicit Close is not a lost connection
+	k.everConnected = false
 
 	return err
 }
 
+// reset drops a connection whose state is unknown, e.g. after a partial
+// read, so the next command redials.
+func (k *Kitty) reset() {
+	if k.conn != nil {
+		k.conn.Close()
+	}
+	k.conn = nil
+	k.reader = nil
+	k.connected = false
+}
+
 func packMsg(msg []byte) []byte {
 	return append(append(kittyMsgPrefix, msg...), kittyMsgSuffix...)
 }
@@ -126,32 +204,101 @@ func (k *Kitty) readFrame() ([]byte, error) {
 	}
 }
 
-// For dispatching commands only, no response is assumed. For $(kitty --version) > v0.42.0
+// Dispatch sends a command and waits for kitty to acknowledge it, the
+// response body is discarded. Use DispatchAsync to skip the response
+// entirely. For $(kitty --version) > v0.42.0
 func (k *Kitty) Dispatch(cmd string, payload any) error {
-	k.mu.Lock()
-	defer k.mu.Unlock()
-
-	if err := k.ensureConnected(); err != nil {
-		return err
-	}
+	return k.DispatchContext(context.Background(), cmd, payload)
+}
 
-	_, err := k.command(cmd, payload)
+// DispatchContext is Dispatch bounded by ctx. The deadline of ctx applies
+// to the socket, and cancelling ctx aborts a command waiting on kitty; the
+// connection is then dropped and redialed by the next command.
+func (k *Kitty) DispatchContext(ctx context.Context, cmd string, payload any) error {
+	_, err := k.roundTrip(ctx, cmd, payload)
 	return err
 }
 
 // Like Dispatch but response is returned.
 func (k *Kitty) Command(cmd string, payload any) (map[string]any, error) {
-	k.mu.Lock()
-	defer k.mu.Unlock()
+	return k.CommandContext(context.Background(), cmd, payload)
+}
+
+// CommandContext is Command bounded by ctx, see DispatchContext.
+func (k *Kitty) CommandContext(ctx context.Context, cmd string, payload any) (map[string]any, error) {
+	respBytes, err := k.roundTrip(ctx, cmd, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp map[string]any
+	if err = json.Unmarshal(respBytes, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return resp, nil
+}
+
+// Do sends a typed request and decodes the "data" field of the response into
+// data. data may be nil when the response carries nothing of interest.
+func (k *Kitty) Do(req Request, data any) error {
+	return k.DoContext(context.Background(), req, data)
+}
+
+// DoContext is Do bounded by ctx, see DispatchContext.
+func (k *Kitty) DoContext(ctx context.Context, req Request, data any) error {
+	respBytes, err := k.roundTrip(ctx, req.Cmd(), req)
+	if err != nil {
+		return err
+	}
+
+	if data == nil {
+		return nil
+	}
+
+	var resp kittyResponse
+	if err = json.Unmarshal(respBytes, &resp); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
 
-	if err := k.ensureConnected(); err != nil {
+	return decodeData(resp.Data, data)
+}
+
+// command writes a single command and returns the raw response after
+// checking that kitty reported success.
+func (k *Kitty) command(cmd string, payload any) ([]byte, error) {
+	msg, err := k.encodeCommand(cmd, payload, false)
+	if err != nil {
 		return nil, err
 	}
+	if err := k.write(msg); err != nil {
+		return nil, err
+	}
+	return k.readResponse()
+}
+
+// encodeCommand builds the framed message for a command, encrypted when
+// a password is set. Must be called with k.mu held.
+// selfWindowID returns the window commands are sent from, see SetWindowID,
+// falling back to the window the panel announced in its handshake. It is
+// 0 while neither is known.
+func (k *Kitty) selfWindowID() int {
+	k.mu.Lock()
+	id, peer := k.windowID, k.peer
+	k.mu.Unlock()
 
-	return k.command(cmd, payload)
+	if id > 0 {
+		return id
+	}
+	if peer != nil {
+		if hello, ok := peer(); ok {
+			return hello.WindowID
+		}
+	}
+	return 0
 }
 
-func (k *Kitty) command(cmd string, payload any) (map[string]any, error) {
+func (k *Kitty) encodeCommand(cmd string, payload any, noResponse bool) ([]byte, error) {
 	var p []byte
 	var err error
 	// easiest way to induce omitempty for payload
@@ -162,10 +309,26 @@ func (k *Kitty) command(cmd string, payload any) (map[string]any, error) {
 		}
 	}
 
+	// a restarted panel runs in a new window of a kitty with a new key
+	var hello Hello
+	if k.peer != nil {
+		hello, _ = k.peer()
+	}
+
 	msg := kittySockMsg{
-		Command: cmd,
-		Version: kittyMinVersion,
-		Payload: p,
+		Command:    cmd,
+		Version:    kittyMinVersion,
+		NoResponse: noResponse,
+		Payload:    p,
+	}
+	if id := k.windowID; id > 0 {
+		msg.KittyWindowId = uint64(id)
+	} else if hello.WindowID > 0 {
+		msg.KittyWindowId = uint64(hello.WindowID)
+	}
+	if k.password != "" {
+		msg.Password = k.password
+		msg.Timestamp = timestamp()
 	}
 
 	msgBytes, err := json.Marshal(msg)
@@ -173,92 +336,185 @@ func (k *Kitty) command(cmd string, payload any) (map[string]any, error) {
 		return nil, fmt.Errorf("failed to mashal message: %w", err)
 	}
 
-	_, err = k.conn.Write(packMsg(msgBytes))
-	if err != nil {
-		k.connected = false
-		return nil, fmt.Errorf("failed to write message: %w", err)
+	if k.password != "" {
+		pub := k.publicKey
+		if hello.PublicKey != "" {
+			if pub, err = parsePublicKey(hello.PublicKey); err != nil {
+				return nil, err
+			}
+		}
+		if pub == nil {
+			return nil, fmt.Errorf("kitty public key unknown, cannot encrypt command")
+		}
+		msgBytes, err = encrypt(msgBytes, pub)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt message: %w", err)
+		}
 	}
+	return packMsg(msgBytes), nil
+}
+
+func (k *Kitty) write(msg []byte) error {
+	if _, err := k.conn.Write(msg); err != nil {
+		k.reset()
+		return &connError{err: fmt.Errorf("failed to write message: %w", err), sent: true}
+	}
+	return nil
+}
 
-	if _, err = io.ReadFull(k.reader, make([]byte, len(kittyMsgPrefix))); err != nil {
-		k.connected = false
-		return nil, fmt.Errorf("failed to read response header: %w", err)
+// readResponse reads one response and checks that kitty reported success.
+func (k *Kitty) readResponse() ([]byte, error) {
+	if _, err := io.ReadFull(k.reader, make([]byte, len(kittyMsgPrefix))); err != nil {
+		k.reset()
+		return nil, &connError{err: fmt.Errorf("failed to read response header: %w", err), sent: true}
 	}
 
 	respBytes, err := k.readFrame()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		k.reset()
+		return nil, &connError{err: fmt.Errorf("failed to read response: %w", err), sent: true}
 	}
 
-	var resp map[string]any
+	var resp kittyResponse
 	if err = json.Unmarshal(respBytes, &resp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	respOk, ok := resp["ok"].(bool)
-	if !ok {
+	if resp.Ok == nil {
 		return nil, fmt.Errorf("invalid response schema: field 'ok' is missing/invalid.")
 	}
 
-	if !respOk {
-		respError, ok := resp["error"].(string)
-		if !ok {
+	if !*resp.Ok {
+		if resp.Error == nil {
 			return nil, fmt.Errorf("invalid response schema: field 'error' is missing/invalid")
 		}
 
-		return nil, fmt.Errorf("kitty error: %s", respError)
+		return nil, fmt.Errorf("kitty error: %s", *resp.Error)
 	}
 
-	return resp, nil
+	return respBytes, nil
 }
 
 func (k *Kitty) SetFontSize(size int) error {
-	return k.Dispatch("set-font-size", map[string]int{"size": size})
+	return k.SetFontSizeContext(context.Background(), size)
+}
+
+func (k *Kitty) SetFontSizeContext(ctx context.Context, size int) error {
+	if err := k.DoContext(ctx, SetFontSizeRequest{Size: float64(size)}, nil); err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	k.fontSize = float64(size)
+	k.mu.Unlock()
+	return nil
 }
 
 func (k *Kitty) SetOpacity(opacity float64) error {
-	return k.Dispatch("set-background-opacity", map[string]float64{"opacity": opacity})
+	return k.SetOpacityContext(context.Background(), opacity)
+}
+
+func (k *Kitty) SetOpacityContext(ctx context.Context, opacity float64) error {
+	return k.DoContext(ctx, SetBackgroundOpacityRequest{Opacity: opacity}, nil)
 }
 
 func (k *Kitty) Resize(columns, lines int) error {
-	return k.Dispatch("resize-os-window", map[string]any{
-		"action":      "os-panel",
-		"incremental": true,
-		"os_panel": []string{
+	return k.ResizeContext(context.Background(), columns, lines)
+}
+
+func (k *Kitty) ResizeContext(ctx context.Context, columns, lines int) error {
+	return k.DoContext(ctx, resizeRequest(columns, lines), nil)
+}
+
+func resizeRequest(columns, lines int) ResizeOSWindowRequest {
+	return ResizeOSWindowRequest{
+		Action:      "os-panel",
+		Incremental: true,
+		OSPanel: []string{
 			fmt.Sprintf("lines=%d", lines),
 			fmt.Sprintf("columns=%d", columns),
 			// fmt.Sprintf("edge=%s", edge),
 			// fmt.Sprintf("layer=%s", layer),
 		},
-	})
+	}
 }
 
 func (k *Kitty) Move(x, y int) error {
-	return k.Dispatch("resize-os-window", map[string]any{
-		"action":      "os-panel",
-		"incremental": true,
-		"os_panel": []string{
+	return k.MoveContext(context.Background(), x, y)
+}
+
+func (k *Kitty) MoveContext(ctx context.Context, x, y int) error {
+	return k.DoContext(ctx, moveRequest(x, y), nil)
+}
+
+func moveRequest(x, y int) ResizeOSWindowRequest {
+	return ResizeOSWindowRequest{
+		Action:      "os-panel",
+		Incremental: true,
+		OSPanel: []string{
 			fmt.Sprintf("margin-left=%d", x),
 			fmt.Sprintf("margin-top=%d", y),
 			// fmt.Sprintf("edge=%s", edge),
 			// fmt.Sprintf("layer=%s", layer),
 		},
-	})
+	}
+}
+
+// SetMargins replaces all four margins of the panel. Unlike Move, margins
+// that are zero in m are reset rather than left unchanged.
+func (k *Kitty) SetMargins(m Margins) error {
+	return k.SetMarginsContext(context.Background(), m)
+}
+
+func (k *Kitty) SetMarginsContext(ctx context.Context, m Margins) error {
+	return k.DoContext(ctx, marginsRequest(m), nil)
+}
+
+func marginsRequest(m Margins) ResizeOSWindowRequest {
+	return ResizeOSWindowRequest{
+		Action:      "os-panel",
+		Incremental: true,
+		OSPanel: []string{
+			fmt.Sprintf("margin-top=%d", m.Top),
+			fmt.Sprintf("margin-bottom=%d", m.Bottom),
+			fmt.Sprintf("margin-left=%d", m.Left),
+			fmt.Sprintf("margin-right=%d", m.Right),
+		},
+	}
 }
 
 func (k *Kitty) Show() error {
-	return k.Dispatch("resize-os-window", map[string]string{
-		"action": "show",
-	})
+	return k.ShowContext(context.Background())
+}
+
+func (k *Kitty) ShowContext(ctx context.Context) error {
+	if err := k.DoContext(ctx, ResizeOSWindowRequest{Action: "show"}, nil); err != nil {
+		return err
+	}
+	k.setVisible(true)
+	return nil
 }
 
 func (k *Kitty) Hide() error {
-	return k.Dispatch("resize-os-window", map[string]string{
-		"action": "hide",
-	})
+	return k.HideContext(context.Background())
+}
+
+func (k *Kitty) HideContext(ctx context.Context) error {
+	if err := k.DoContext(ctx, ResizeOSWindowRequest{Action: "hide"}, nil); err != nil {
+		return err
+	}
+	k.setVisible(false)
+	return nil
 }
 
 func (k *Kitty) ToggleVisibility() error {
-	return k.Dispatch("resize-os-window", map[string]string{
-		"action": "toggle-visibility",
-	})
+	return k.ToggleVisibilityContext(context.Background())
+}
+
+func (k *Kitty) ToggleVisibilityContext(ctx context.Context) error {
+	if err := k.DoContext(ctx, ResizeOSWindowRequest{Action: "toggle-visibility"}, nil); err != nil {
+		return err
+	}
+	k.toggleVisible()
+	return nil
 }
@@ -0,0 +1,217 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// GracePeriod is how long a PanelHandlerContext may take to return after
+// its context is cancelled before the panel process exits anyway. The host
+// waits as long for a panel it stopped before signalling kitty.
+var GracePeriod = 5 * time.Second
+
+// ErrShutdown is the cause of a cancelled handler context, wrapped with the
+// reason, see context.Cause.
+var ErrShutdown = errors.New("panel shutting down")
+
+// PanelHandlerContext is a PanelHandler whose context is cancelled when the
+// panel is asked to go away: Panel.Interrupt or Panel.Shutdown on the host,
+// SIGTERM or SIGINT, SIGHUP from kitty closing the window, or death of the
+// kitty process.
+// The handler then has GracePeriod to return; a second signal ends it
+// right away. runPanel prefers RunContext over RunInstance and Run.
+type PanelHandlerContext interface {
+	PanelHandler
+	RunContext(ctx context.Context, in *Instance) int
+}
+
+type PanelContextFunc func(ctx context.Context, in *Instance) int
+
+func (f PanelContextFunc) Run(k *Kitty, rw io.ReadWriter) int {
+	return f(context.Background(), &Instance{Kitty: k, RW: rw})
+}
+
+func (f PanelContextFunc) RunContext(ctx context.Context, in *Instance) int {
+	return f(ctx, in)
+}
+
+func RegisterContextFunc(name string, panel PanelContextFunc) {
+	Register(name, panel)
+}
+
+// runContext runs h until it returns, cancelling its context when stopped
+// is closed or the process is told to exit.
+func runContext(h PanelHandlerContext, in *Instance, stopped <-chan struct{}) int {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+	defer signal.Stop(sigs)
+
+	// kitty going away without closing the window first
+	unix.Prctl(unix.PR_SET_PDEATHSIG, uintptr(syscall.SIGTERM), 0, 0, 0)
+	if os.Getppid() == 1 {
+		cancel(fmt.Errorf("%w: parent exited", ErrShutdown))
+	}
+
+	done := make(chan int, 1)
+	go func() {
+		done <- h.RunContext(ctx, in)
+	}()
+
+	select {
+	case code := <-done:
+		return code
+	case sig := <-sigs:
+		cancel(fmt.Errorf("%w: %v", ErrShutdown, sig))
+	case <-stopped:
+		cancel(fmt.Errorf("%w: stopped by host", ErrShutdown))
+	case <-ctx.Done():
+	}
+
+	grace := time.NewTimer(GracePeriod)
+	defer grace.Stop()
+
+	select {
+	case code := <-done:
+		return code
+	case sig := <-sigs:
+		fmt.Fprintf(os.Stderr, "katnip: %v, exiting\n", sig)
+	case <-grace.C:
+		fmt.Fprintf(os.Stderr, "katnip: handler did not return within %v, exiting\n", GracePeriod)
+	}
+	return 1
+}
+
+// Interrupt asks the panel handler to exit. It sends a stop message over
+// shared memory: a PanelHandlerContext sees its context cancelled, other
+// handlers read io.EOF from their stream once the data sent before is
+// consumed. Without a completed handshake the handler gets SIGTERM
+// instead, through kitty's signal-child if its pid is unknown.
+func (p *Panel) Interrupt() error {
+	return p.interrupt(context.Background())
+}
+
+func (p *Panel) interrupt(ctx context.Context) error {
+	if stream := p.currentStream(); stream != nil {
+		if stream.stop() {
+			return nil
+		}
+		if done, err := stream.finished(); done && err == nil {
+			hello, _ := stream.result(context.Background())
+			if hello.PID > 0 {
+				return syscall.Kill(hello.PID, syscall.SIGTERM)
+			}
+		}
+	}
+	return p.Kitty().DoContext(ctx, SignalChildRequest{Signals: []string{"SIGTERM"}}, nil)
+}
+
+// KillDelay is how long Shutdown waits for kitty to exit after SIGTERM
+// before sending SIGKILL.
+var KillDelay = 2 * time.Second
+
+// Shutdown stops the panel gracefully and releases its resources. The
+// handler is asked to exit with Interrupt; if the panel is still running
+// after GracePeriod or when ctx is done, kitty gets SIGTERM and, KillDelay
+// later, SIGKILL. The shared memory stream is then closed and the socket
+// and helper files are removed, so the panel cannot be started again.
+//
+// An error is returned if the panel had to be killed.
+func (p *Panel) Shutdown(ctx context.Context) error {
+	var err error
+	if p.running() {
+		err = p.terminate(ctx)
+	}
+	p.release()
+	return err
+}
+
+// release closes the client and the shared memory stream and removes the
+// socket and helper files of a panel that is not running.
+func (p *Panel) release() {
+	p.mu.Lock()
+	kitty := p.kitty
+	p.kitty = nil
+	p.mu.Unlock()
+	if kitty != nil {
+		kitty.Close()
+	}
+
+	p.cleanup()
+	os.Remove(p.socketPath)
+	for _, suffix := range socketSuffixes {
+		os.Remove(p.socketPath + suffix)
+	}
+}
+
+func (p *Panel) running() bool {
+	if p.exited == nil {
+		return false
+	}
+	select {
+	case <-p.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+func (p *Panel) terminate(ctx context.Context) error {
+	if err := p.interrupt(ctx); err == nil {
+		grace := time.NewTimer(GracePeriod)
+		defer grace.Stop()
+
+		select {
+		case <-p.exited:
+			return nil
+		case <-grace.C:
+		case <-ctx.Done():
+		}
+	}
+
+	p.Cmd.Process.Signal(syscall.SIGTERM)
+	timer := time.NewTimer(KillDelay)
+	defer timer.Stop()
+
+	select {
+	case <-p.exited:
+		return nil
+	case <-timer.C:
+	}
+
+	p.Cmd.Process.Kill()
+	<-p.exited
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("panel %q killed: %w", p.name, err)
+	}
+	return fmt.Errorf("panel %q killed", p.name)
+}
@@ -0,0 +1,248 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// Match is a kitty match expression selecting windows or tabs, such as
+// "id:3" or "title:^htop and not state:focused". Build one from the Match
+// functions and combine them with And, Or and Not; String gives the value
+// for the Match fields of requests. Patterns are Python regular
+// expressions, as kitty interprets them.
+type Match string
+
+func (m Match) String() string {
+	return string(m)
+}
+
+// And matches what both m and o match.
+func (m Match) And(o Match) Match {
+	return Match("(" + string(m) + ") and (" + string(o) + ")")
+}
+
+// Or matches what either m or o matches.
+func (m Match) Or(o Match) Match {
+	return Match("(" + string(m) + ") or (" + string(o) + ")")
+}
+
+// Not matches what m does not.
+func Not(m Match) Match {
+	return Match("not (" + string(m) + ")")
+}
+
+func matchTerm(field, query string) Match {
+	return Match(field + ":" + quoteQuery(query))
+}
+
+// quoteQuery quotes queries the match parser would otherwise split.
+func quoteQuery(q string) string {
+	if q != "" && !strings.ContainsAny(q, " \t\n()\"") {
+		return q
+	}
+	q = strings.ReplaceAll(q, `\`, `\\`)
+	return `"` + strings.ReplaceAll(q, `"`, `\"`) + `"`
+}
+
+// MatchID matches the window (or tab) with the given id. Negative ids
+// count back from the most recently created one.
+func MatchID(id int) Match {
+	return matchTerm("id", strconv.Itoa(id))
+}
+
+func MatchTitle(pattern string) Match {
+	return matchTerm("title", pattern)
+}
+
+func MatchPID(pid int) Match {
+	return matchTerm("pid", strconv.Itoa(pid))
+}
+
+func MatchCwd(pattern string) Match {
+	return matchTerm("cwd", pattern)
+}
+
+// MatchCmdline matches windows whose command line has an argument matching
+// pattern.
+func MatchCmdline(pattern string) Match {
+	return matchTerm("cmdline", pattern)
+}
+
+// MatchEnv matches windows whose environment has key with a value
+// matching pattern.
+func MatchEnv(key, pattern string) Match {
+	return matchTerm("env", key+"="+pattern)
+}
+
+// MatchVar matches windows with a user variable, see kitty's set-user-var.
+func MatchVar(name, pattern string) Match {
+	return matchTerm("var", name+"="+pattern)
+}
+
+// MatchState matches windows in a state: active, focused, needs_attention,
+// parent_active, parent_focused, self or overlay_parent.
+func MatchState(state string) Match {
+	return matchTerm("state", state)
+}
+
+// MatchRecent matches the n-th most recently active window, 0 being the
+// active one.
+func MatchRecent(n int) Match {
+	return matchTerm("recent", strconv.Itoa(n))
+}
+
+// SetWindowID sets the kitty window commands are sent from, which is what
+// "self" refers to in requests and state:self in matches. Inside a panel it
+// defaults to the panel's window, for Panel.Kitty to the panel's window
+// once the handshake is done.
+func (k *Kitty) SetWindowID(id int) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.windowID = id
+}
+
+// Target addresses the windows matched by an expression, e.g. extra
+// windows a panel opened with Launch.
+type Target struct {
+	k     *Kitty
+	match Match
+}
+
+// Target returns a handle on the windows matching m.
+func (k *Kitty) Target(m Match) *Target {
+	return &Target{k: k, match: m}
+}
+
+// WindowTarget returns a handle on the window with the given id, such as
+// one returned by Launch.
+func (k *Kitty) WindowTarget(id int) *Target {
+	return k.Target(MatchID(id))
+}
+
+func (t *Target) Match() Match {
+	return t.match
+}
+
+// Windows lists the matched windows.
+func (t *Target) Windows() ([]Window, error) {
+	return t.WindowsContext(context.Background())
+}
+
+func (t *Target) WindowsContext(ctx context.Context) ([]Window, error) {
+	osWindows, err := t.k.LsContext(ctx, LsRequest{Match: t.match.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	var windows []Window
+	for _, osw := range osWindows {
+		for _, tab := range osw.Tabs {
+			windows = append(windows, tab.Windows...)
+		}
+	}
+	return windows, nil
+}
+
+func (t *Target) SendText(text string) error {
+	return t.SendTextContext(context.Background(), text)
+}
+
+func (t *Target) SendTextContext(ctx context.Context, text string) error {
+	return t.k.SendTextContext(ctx, SendTextRequest{Text: text, Match: t.match.String()})
+}
+
+// GetText returns the screen contents of the first matched window.
+func (t *Target) GetText() (string, error) {
+	return t.GetTextContext(context.Background())
+}
+
+func (t *Target) GetTextContext(ctx context.Context) (string, error) {
+	return t.k.GetTextContext(ctx, GetTextRequest{Match: t.match.String()})
+}
+
+func (t *Target) SetTitle(title string) error {
+	return t.SetTitleContext(context.Background(), title)
+}
+
+func (t *Target) SetTitleContext(ctx context.Context, title string) error {
+	return t.k.SetWindowTitleContext(ctx, SetWindowTitleRequest{Title: title, Match: t.match.String()})
+}
+
+func (t *Target) SetColors(colors map[string]uint32) error {
+	return t.SetColorsContext(context.Background(), colors)
+}
+
+func (t *Target) SetColorsContext(ctx context.Context, colors map[string]uint32) error {
+	return t.k.SetColorsContext(ctx, SetColorsRequest{Colors: colors, MatchWindow: t.match.String()})
+}
+
+func (t *Target) SetOpacity(opacity float64) error {
+	return t.SetOpacityContext(context.Background(), opacity)
+}
+
+func (t *Target) SetOpacityContext(ctx context.Context, opacity float64) error {
+	return t.k.DoContext(ctx, SetBackgroundOpacityRequest{Opacity: opacity, MatchWindow: t.match.String()}, nil)
+}
+
+func (t *Target) Focus() error {
+	return t.FocusContext(context.Background())
+}
+
+func (t *Target) FocusContext(ctx context.Context) error {
+	return t.k.FocusWindowContext(ctx, FocusWindowRequest{Match: t.match.String()})
+}
+
+// Signal sends signals, by name such as "SIGTERM", to the programs running
+// in the matched windows.
+func (t *Target) Signal(signals ...string) error {
+	return t.SignalContext(context.Background(), signals...)
+}
+
+func (t *Target) SignalContext(ctx context.Context, signals ...string) error {
+	return t.k.SignalChildContext(ctx, SignalChildRequest{Signals: signals, Match: t.match.String()})
+}
+
+// Close closes the matched windows. Matching nothing is not an error.
+func (t *Target) Close() error {
+	return t.CloseContext(context.Background())
+}
+
+func (t *Target) CloseContext(ctx context.Context) error {
+	return t.k.CloseWindowContext(ctx, CloseWindowRequest{Match: t.match.String(), IgnoreNoMatch: true})
+}
+
+// Launch opens a window next to the first matched one and returns a
+// handle on it. req.Match is overwritten.
+func (t *Target) Launch(req LaunchRequest) (*Target, error) {
+	return t.LaunchContext(context.Background(), req)
+}
+
+func (t *Target) LaunchContext(ctx context.Context, req LaunchRequest) (*Target, error) {
+	req.Match = t.match.String()
+	id, err := t.k.LaunchContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return t.k.WindowTarget(id), nil
+}
@@ -0,0 +1,380 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Output is a monitor as reported by the compositor.
+type Output struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	X           int     `json:"x"`
+	Y           int     `json:"y"`
+	Width       int     `json:"width"`
+	Height      int     `json:"height"`
+	Scale       float64 `json:"scale,omitempty"`
+	Focused     bool    `json:"focused,omitempty"`
+	Enabled     bool    `json:"enabled"`
+}
+
+// OutputProvider enumerates the outputs of the running compositor.
+type OutputProvider interface {
+	Outputs(ctx context.Context) ([]Output, error)
+}
+
+// CommandOutputs runs a command and parses its output.
+type CommandOutputs struct {
+	Args  []string
+	Parse func(data []byte) ([]Output, error)
+}
+
+func (c CommandOutputs) Outputs(ctx context.Context) ([]Output, error) {
+	if len(c.Args) == 0 {
+		return nil, fmt.Errorf("no command given")
+	}
+	out, err := exec.CommandContext(ctx, c.Args[0], c.Args[1:]...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", c.Args[0], err)
+	}
+	return c.Parse(out)
+}
+
+// FileOutputs reads outputs from a file on every call, a stand-in for a
+// compositor in tests. Parse defaults to ParseOutputs.
+type FileOutputs struct {
+	Path  string
+	Parse func(data []byte) ([]Output, error)
+}
+
+func (f FileOutputs) Outputs(ctx context.Context) ([]Output, error) {
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	if f.Parse == nil {
+		return ParseOutputs(data)
+	}
+	return f.Parse(data)
+}
+
+func WlrRandrOutputs() OutputProvider {
+	return CommandOutputs{Args: []string{"wlr-randr", "--json"}, Parse: ParseWlrRandr}
+}
+
+func HyprctlOutputs() OutputProvider {
+	return CommandOutputs{Args: []string{"hyprctl", "monitors", "all", "-j"}, Parse: ParseHyprctl}
+}
+
+func SwaymsgOutputs() OutputProvider {
+	return CommandOutputs{Args: []string{"swaymsg", "-r", "-t", "get_outputs"}, Parse: ParseSwaymsg}
+}
+
+// DetectOutputs picks a provider for the running compositor, falling back
+// to wlr-randr.
+func DetectOutputs() OutputProvider {
+	switch {
+	case os.Getenv("HYPRLAND_INSTANCE_SIGNATURE") != "":
+		return HyprctlOutputs()
+	case os.Getenv("SWAYSOCK") != "":
+		return SwaymsgOutputs()
+	default:
+		return WlrRandrOutputs()
+	}
+}
+
+// ParseOutputs parses a JSON array of Output.
+func ParseOutputs(data []byte) ([]Output, error) {
+	var outputs []Output
+	if err := json.Unmarshal(data, &outputs); err != nil {
+		return nil, fmt.Errorf("failed to parse outputs: %w", err)
+	}
+	return outputs, nil
+}
+
+// ParseWlrRandr parses the output of `wlr-randr --json`.
+func ParseWlrRandr(data []byte) ([]Output, error) {
+	var raw []struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Enabled     bool   `json:"enabled"`
+		Modes       []struct {
+			Width   int  `json:"width"`
+			Height  int  `json:"height"`
+			Current bool `json:"current"`
+		} `json:"modes"`
+		Position struct {
+			X int `json:"x"`
+			Y int `json:"y"`
+		} `json:"position"`
+		Scale float64 `json:"scale"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse wlr-randr output: %w", err)
+	}
+
+	outputs := make([]Output, 0, len(raw))
+	for _, r := range raw {
+		o := Output{
+			Name:        r.Name,
+			Description: r.Description,
+			X:           r.Position.X,
+			Y:           r.Position.Y,
+			Scale:       r.Scale,
+			Enabled:     r.Enabled,
+		}
+		for _, m := range r.Modes {
+			if m.Current {
+				o.Width, o.Height = m.Width, m.Height
+			}
+		}
+		outputs = append(outputs, o)
+	}
+	return outputs, nil
+}
+
+// ParseHyprctl parses the output of `hyprctl monitors -j`.
+func ParseHyprctl(data []byte) ([]Output, error) {
+	var raw []struct {
+		Name        string  `json:"name"`
+		Description string  `json:"description"`
+		X           int     `json:"x"`
+		Y           int     `json:"y"`
+		Width       int     `json:"width"`
+		Height      int     `json:"height"`
+		Scale       float64 `json:"scale"`
+		Focused     bool    `json:"focused"`
+		Disabled    bool    `json:"disabled"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse hyprctl output: %w", err)
+	}
+
+	outputs := make([]Output, 0, len(raw))
+	for _, r := range raw {
+		outputs = append(outputs, Output{
+			Name:        r.Name,
+			Description: r.Description,
+			X:           r.X,
+			Y:           r.Y,
+			Width:       r.Width,
+			Height:      r.Height,
+			Scale:       r.Scale,
+			Focused:     r.Focused,
+			Enabled:     !r.Disabled,
+		})
+	}
+	return outputs, nil
+}
+
+// ParseSwaymsg parses the output of `swaymsg -r -t get_outputs`.
+func ParseSwaymsg(data []byte) ([]Output, error) {
+	var raw []struct {
+		Name   string `json:"name"`
+		Make   string `json:"make"`
+		Model  string `json:"model"`
+		Active bool   `json:"active"`
+		Rect   struct {
+			X      int `json:"x"`
+			Y      int `json:"y"`
+			Width  int `json:"width"`
+			Height int `json:"height"`
+		} `json:"rect"`
+		Scale   float64 `json:"scale"`
+		Focused bool    `json:"focused"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse swaymsg output: %w", err)
+	}
+
+	outputs := make([]Output, 0, len(raw))
+	for _, r := range raw {
+		outputs = append(outputs, Output{
+			Name:        r.Name,
+			Description: r.Make + " " + r.Model,
+			X:           r.Rect.X,
+			Y:           r.Rect.Y,
+			Width:       r.Rect.Width,
+			Height:      r.Rect.Height,
+			Scale:       r.Scale,
+			Focused:     r.Focused,
+			Enabled:     r.Active,
+		})
+	}
+	return outputs, nil
+}
+
+// OutputPanels keeps one panel per enabled output, adding and removing
+// panels as outputs appear and disappear. Panels are run by a Supervisor.
+type OutputPanels struct {
+	// registered handler name, passed to NewPanel
+	Name string
+	// template for every panel, OutputName is set per output
+	Config Config
+
+	// default: DetectOutputs()
+	Provider OutputProvider
+	// how often outputs are re-enumerated, default: 2s
+	PollInterval time.Duration
+
+	// optional, outputs for which it returns false get no panel
+	Filter func(o Output) bool
+	// optional, adjusts the config of a single output's panel
+	Configure func(o Output, c *Config)
+
+	// panels are added to Supervisor, which is created and started by
+	// Run if nil
+	Supervisor *Supervisor
+
+	mu     sync.Mutex
+	panels map[string]*Panel
+}
+
+// Panels returns the current panels keyed by output name.
+func (m *OutputPanels) Panels() map[string]*Panel {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	panels := make(map[string]*Panel, len(m.panels))
+	for name, p := range m.panels {
+		panels[name] = p
+	}
+	return panels
+}
+
+// Run enumerates outputs until ctx is done, then removes all its panels.
+// Errors from the provider are returned only if the first enumeration
+// fails; later ones keep the current panels.
+func (m *OutputPanels) Run(ctx context.Context) error {
+	provider := m.Provider
+	if provider == nil {
+		provider = DetectOutputs()
+	}
+	interval := m.PollInterval
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+
+	sup := m.Supervisor
+	if sup == nil {
+		sup = NewSupervisor(SupervisorConfig{})
+		if err := sup.Start(ctx); err != nil {
+			return err
+		}
+		defer sup.Stop()
+	}
+
+	m.mu.Lock()
+	m.panels = map[string]*Panel{}
+	m.mu.Unlock()
+	defer m.removeAll(sup)
+
+	outputs, err := provider.Outputs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to enumerate outputs: %w", err)
+	}
+	m.sync(sup, outputs)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if outputs, err := provider.Outputs(ctx); err == nil {
+			m.sync(sup, outputs)
+		}
+	}
+}
+
+func (m *OutputPanels) sync(sup *Supervisor, outputs []Output) {
+	m.mu.Lock()
+	var removed []*Panel
+	defer func() {
+		m.mu.Unlock()
+		retire(sup, removed)
+	}()
+
+	wanted := map[string]Output{}
+	for _, o := range outputs {
+		if o.Enabled && (m.Filter == nil || m.Filter(o)) {
+			wanted[o.Name] = o
+		}
+	}
+
+	for name, p := range m.panels {
+		if _, ok := wanted[name]; !ok {
+			removed = append(removed, p)
+			delete(m.panels, name)
+		}
+	}
+
+	names := make([]string, 0, len(wanted))
+	for name := range wanted {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, ok := m.panels[name]; ok {
+			continue
+		}
+		config := m.Config
+		config.OutputName = name
+		if m.Configure != nil {
+			m.Configure(wanted[name], &config)
+		}
+		p := NewPanel(m.Name, config)
+		m.panels[name] = p
+		sup.Add(p)
+	}
+}
+
+func (m *OutputPanels) removeAll(sup *Supervisor) {
+	m.mu.Lock()
+	var removed []*Panel
+	for name, p := range m.panels {
+		removed = append(removed, p)
+		delete(m.panels, name)
+	}
+	m.mu.Unlock()
+
+	retire(sup, removed)
+}
+
+// retire stops supervising panels, which shuts them down, and releases
+// their resources.
+func retire(sup *Supervisor, panels []*Panel) {
+	for _, p := range panels {
+		sup.Remove(p)
+		p.release()
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFileOutputs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outputs.json")
+	data := `[{"name": "DP-1", "width": 2560, "height": 1440, "enabled": true},
+		{"name": "HDMI-A-1", "x": 2560, "enabled": false}]`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := FileOutputs{Path: path}.Outputs(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Output{
+		{Name: "DP-1", Width: 2560, Height: 1440, Enabled: true},
+		{Name: "HDMI-A-1", X: 2560},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Outputs() = %+v, want %+v", got, want)
+	}
+
+	hypr := `[{"name": "eDP-1", "width": 1920, "height": 1080, "disabled": false}]`
+	if err := os.WriteFile(path, []byte(hypr), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err = FileOutputs{Path: path, Parse: ParseHyprctl}.Outputs(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []Output{{Name: "eDP-1", Width: 1920, Height: 1080, Enabled: true}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Outputs() with ParseHyprctl = %+v, want %+v", got, want)
+	}
+
+	if _, err := (FileOutputs{Path: path + ".missing"}).Outputs(t.Context()); err == nil {
+		t.Error("Outputs() of a missing file succeeded")
+	}
+	os.WriteFile(path, []byte("{"), 0o600)
+	if _, err := (FileOutputs{Path: path}).Outputs(t.Context()); err == nil {
+		t.Error("Outputs() of invalid json succeeded")
+	}
+}
+
+func outputNames(panels map[string]*Panel) []string {
+	var names []string
+	for name, p := range panels {
+		if p.config.OutputName != name {
+			panic("panel for " + name + " has OutputName " + p.config.OutputName)
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestOutputPanelsSync(t *testing.T) {
+	// never started, so panels are tracked but not run
+	sup := NewSupervisor(SupervisorConfig{})
+	m := &OutputPanels{
+		Name:   "bar",
+		Filter: func(o Output) bool { return o.Name != "HEADLESS-1" },
+		Configure: func(o Output, c *Config) {
+			c.Size.X = o.Width / 10
+		},
+		panels: map[string]*Panel{},
+	}
+
+	steps := []struct {
+		outputs []Output
+		want    []string
+	}{
+		{
+			outputs: []Output{
+				{Name: "DP-1", Width: 2560, Enabled: true},
+				{Name: "HDMI-A-1", Width: 1920, Enabled: true},
+				{Name: "HEADLESS-1", Enabled: true},
+				{Name: "eDP-1", Enabled: false},
+			},
+			want: []string{"DP-1", "HDMI-A-1"},
+		},
+		{
+			outputs: []Output{
+				{Name: "DP-1", Width: 2560, Enabled: true},
+				{Name: "eDP-1", Width: 1920, Enabled: true},
+			},
+			want: []string{"DP-1", "eDP-1"},
+		},
+		{outputs: nil, want: nil},
+	}
+
+	var kept, removed *Panel
+	for i, step := range steps {
+		before := m.Panels()
+		m.sync(sup, step.outputs)
+		after := m.Panels()
+
+		if got := outputNames(after); !slices.Equal(got, step.want) {
+			t.Fatalf("step %d: panels for %v, want %v", i, got, step.want)
+		}
+		if got := len(sup.panels); got != len(step.want) {
+			t.Errorf("step %d: supervisor has %d panels, want %d", i, got, len(step.want))
+		}
+
+		switch i {
+		case 0:
+			if p := after["DP-1"]; p.config.Size.X != 256 {
+				t.Errorf("Configure not applied: %+v", p.config.Size)
+			}
+		case 1:
+			kept, removed = before["DP-1"], before["HDMI-A-1"]
+			if after["DP-1"] != kept {
+				t.Error("panel of an unchanged output was replaced")
+			}
+		}
+	}
+
+	// removed panels are shut down
+	if removed.shmStream != nil || kept.shmStream != nil {
+		t.Error("removed panels were not shut down")
+	}
+}
+
+func TestOutputPanelsRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outputs.json")
+	write := func(data string) {
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`[{"name": "DP-1", "enabled": true}]`)
+
+	m := &OutputPanels{
+		Name:         "bar",
+		Provider:     FileOutputs{Path: path},
+		PollInterval: 10 * time.Millisecond,
+		Supervisor:   NewSupervisor(SupervisorConfig{}),
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() { done <- m.Run(ctx) }()
+
+	waitPanels := func(want ...string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			got := outputNames(m.Panels())
+			if slices.Equal(got, want) {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("panels for %v, want %v", got, want)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	waitPanels("DP-1")
+	write(`[{"name": "DP-1", "enabled": true}, {"name": "DP-2", "enabled": true}]`)
+	waitPanels("DP-1", "DP-2")
+	// a failed enumeration keeps the current panels
+	write(`not json`)
+	time.Sleep(50 * time.Millisecond)
+	waitPanels("DP-1", "DP-2")
+	write(`[{"name": "DP-2", "enabled": true}]`)
+	waitPanels("DP-2")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	waitPanels()
+
+	if err := (&OutputPanels{Provider: FileOutputs{Path: path + ".missing"}}).Run(t.Context()); err == nil {
+		t.Error("Run succeeded although the first enumeration failed")
+	}
+}
@@ -22,14 +22,20 @@ package katnip
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/codelif/shmstream"
+	"golang.org/x/sys/unix"
 )
 
 type Panel struct {
@@ -38,13 +44,32 @@ type Panel struct {
 	Cmd *exec.Cmd
 
 	name       string
+	handler    string
 	config     Config
 	socketPath string
+	ctx        context.Context
 	started    bool
+	exited     chan struct{}
+	waitErr    error
+
+	// Cmd.Stderr as set by the user, and the tail of the last run
+	stderr     io.Writer
+	stderrTail *tailBuffer
 	shmStream  *shmstream.StreamBuffer
 	shmIo      io.ReadWriter
+	stream     *hostStream
+	files      []string
+	args       []byte
+	argsErr    error
+	channel    *Channel
+	rpc        *RPC
+	kitty      *Kitty
+	mu         sync.Mutex
 }
 
+// PanelHandler runs inside the panel process. rw is the shared memory
+// stream to the host, nil without one; its reads return io.EOF once the
+// host stops the panel, see Panel.Interrupt.
 type PanelHandler interface {
 	Run(k *Kitty, rw io.ReadWriter) int
 }
@@ -89,6 +114,11 @@ type Vector struct {
 	X, Y int
 }
 
+// Margins are in pixels, measured from the edges of the output. A margin
+// towards the edge a panel is anchored to has no effect (e.g. Top for an
+// EdgeBottom panel). Zero is kitty's default of no margin; negative values
+// are passed through and move the panel past the edge where the compositor
+// allows it.
 type Margins struct {
 	Top, Bottom, Left, Right int
 }
@@ -108,8 +138,12 @@ type Config struct {
 	SingleInstance bool
 	InstanceGroup  string
 
-	// Doesn't do anything right now
-	// Margins     Margins
+	// Install a kitty watcher so that Kitty.Events in the panel gets focus,
+	// resize and close events as they happen instead of by polling.
+	Watcher bool
+
+	// Left and Top take precedence over Position when non-zero
+	Margins Margins
 
 	ConfigFile string
 
@@ -119,24 +153,94 @@ type Config struct {
 	// Easier way to add -o options
 	KittyOverrides []string
 
+	// Args is handed to the panel handler, see Instance.Args. It is encoded
+	// as JSON and sent over shared memory, or the environment without it.
+	Args any
+
+	// Require this password for remote control, limited to
+	// RemoteControlActions (e.g. "ls", "set-*") when given. Commands from
+	// the panel's and the host's Kitty are then encrypted. kitty reads the
+	// password from a private config file, the panel gets it over shared
+	// memory, or the environment without it.
+	RemoteControlPassword string
+	RemoteControlActions  []string
+
 	// kitty command to be invoked, default: kitty
 	//
 	// one usecase: when multiple versions of kitty are installed and maintained using symlinks
 	KittyCmd string
 }
 
+// panelConfig is the part of Config a panel process learns about, sent in
+// its environment.
+type panelConfig struct {
+	Edge            Edge    `json:"edge,omitempty"`
+	Layer           Layer   `json:"layer,omitempty"`
+	StartAsHidden   bool    `json:"start_as_hidden,omitempty"`
+	HideOnFocusLoss bool    `json:"hide_on_focus_loss,omitempty"`
+	Margins         Margins `json:"margins"`
+	Size            Vector  `json:"size"`
+}
+
+func (c panelConfig) config() Config {
+	return Config{
+		Edge:            c.Edge,
+		Layer:           c.Layer,
+		StartAsHidden:   c.StartAsHidden,
+		HideOnFocusLoss: c.HideOnFocusLoss,
+		Margins:         c.Margins,
+		Size:            c.Size,
+	}
+}
+
 const kittyCmd = "kitty"
 
 var index uint64 = 0
 
 func NewPanel(name string, config Config) *Panel {
-	socketPath := fmt.Sprintf("/tmp/katnip-%s-%d-%d", name, os.Getpid(), index)
+	return newPanel(name, name, config)
+}
+
+// newPanel creates a panel running the handler registered as handler.
+func newPanel(name, handler string, config Config) *Panel {
+	socketPath := filepath.Join(SocketDir(), fmt.Sprintf("%s%s-%d-%d", socketPrefix, name, os.Getpid(), index))
 	index++
 
+	p := &Panel{
+		name:       name,
+		handler:    handler,
+		config:     config,
+		socketPath: socketPath,
+	}
+	p.args, p.argsErr = encodeArgs(config.Args)
+
+	shmStream, err := shmstream.New(shmstream.Config{Bidirectional: true})
+	if err == nil {
+		p.shmStream = shmStream
+		reader, _ := shmStream.NewReader()
+		writer, _ := shmStream.NewWriter()
+		p.stream = newHostStream(reader, writer)
+		p.shmIo = p.stream
+	}
+
+	p.Cmd = p.newCmd()
+	return p
+}
+
+// newCmd builds the kitty invocation for the panel. It is called once per
+// start attempt since an exec.Cmd can only be run once.
+func (p *Panel) newCmd() *exec.Cmd {
+	config := p.config
 	args := []string{
 		"+kitten", "panel",
-		"--listen-on", "unix:" + socketPath,
-		"-o", "allow_remote_control=socket-only",
+		"--listen-on", "unix:" + p.socketPath,
+	}
+	if config.RemoteControlPassword != "" {
+		// the password itself is in the config written by Start, command
+		// lines are readable by everyone
+		args = append(args, "-o", "allow_remote_control=password")
+	} else {
+		args = append(args, "-o", "allow_remote_control=socket-only")
 	}
 
 	if config.Layer > 0 {
@@ -154,19 +258,41 @@ func NewPanel(name string, config Config) *Panel {
 	if config.Size.Y > 0 {
 		args = append(args, "--lines", strconv.Itoa(config.Size.Y))
 	}
-	if config.Position.X > 0 {
-		args = append(args, "--margin-left", strconv.Itoa(config.Position.X))
+	margins := config.Margins
+	if margins.Left == 0 && config.Position.X > 0 {
+		margins.Left = config.Position.X
+	}
+	if margins.Top == 0 && config.Position.Y > 0 {
+		margins.Top = config.Position.Y
+	}
+	// zero is kitty's default, so only non-zero margins are passed
+	if margins.Top != 0 {
+		args = append(args, "--margin-top", strconv.Itoa(margins.Top))
+	}
+	if margins.Bottom != 0 {
+		args = append(args, "--margin-bottom", strconv.Itoa(margins.Bottom))
 	}
-	if config.Position.Y > 0 {
-		args = append(args, "--margin-top", strconv.Itoa(config.Position.Y))
+	if margins.Left != 0 {
+		args = append(args, "--margin-left", strconv.Itoa(margins.Left))
+	}
+	if margins.Right != 0 {
+		args = append(args, "--margin-right", strconv.Itoa(margins.Right))
 	}
 	if config.ConfigFile != "" {
 		args = append(args, "--config", config.ConfigFile)
+	} else if config.RemoteControlPassword != "" {
+		// any --config replaces the default kitty.conf, so keep loading it
+		if path := kittyConfigFile(); path != "" {
+			args = append(args, "--config", path)
+		}
+	}
+	if config.RemoteControlPassword != "" {
+		args = append(args, "--config", p.rcConfigPath())
 	}
 	if config.Class != "" {
 		args = append(args, "--class", config.Class)
 	} else {
-		args = append(args, "--class", name)
+		args = append(args, "--class", p.name)
 	}
 	if config.OutputName != "" {
 		args = append(args, "--output-name", config.OutputName)
@@ -186,6 +312,11 @@ func NewPanel(name string, config Config) *Panel {
 		args = append(args, "--single-instance")
 	}
 
+	// the script itself is written by Start
+	if config.Watcher {
+		args = append(args, "-o", "watcher="+p.watcherPath())
+	}
+
 	for _, o := range config.KittyOverrides {
 		args = append(args, "-o", o)
 	}
@@ -200,62 +331,308 @@ func NewPanel(name string, config Config) *Panel {
 	if config.KittyCmd != "" {
 		kc = config.KittyCmd
 	}
-	cmd := exec.Command(kc, args...)
+
+	var cmd *exec.Cmd
+	if p.ctx != nil {
+		cmd = exec.CommandContext(p.ctx, kc, args...)
+	} else {
+		cmd = exec.Command(kc, args...)
+	}
 	cmd.SysProcAttr = &syscall.SysProcAttr{Pdeathsig: syscall.SIGTERM}
+	// Stderr is copied through a pipe, which a leftover child of kitty may
+	// keep open after kitty exited
+	cmd.WaitDelay = 2 * time.Second
 
 	cmd.Env = append(os.Environ(),
-		GetEnvPair("INSTANCE", name),
-		GetEnvPair("SOCKET", socketPath),
+		GetEnvPair("INSTANCE", p.handler),
+		GetEnvPair("SOCKET", p.socketPath),
 	)
+	if p.shmStream != nil {
+		cmd.Env = append(cmd.Env, GetEnvPair("SHM_PATH", p.shmStream.Path()))
+	}
+	cmd.Env = append(cmd.Env, GetEnvPair("READY", p.readyPath()))
+	if config.Watcher {
+		cmd.Env = append(cmd.Env, GetEnvPair("EVENTS", p.socketPath+"-events"))
+	}
+	c, _ := json.Marshal(panelConfig{
+		Edge:            config.Edge,
+		Layer:           config.Layer,
+		StartAsHidden:   config.StartAsHidden,
+		HideOnFocusLoss: config.HideOnFocusLoss,
+		Margins:         margins,
+		Size:            config.Size,
+	})
+	cmd.Env = append(cmd.Env, GetEnvPair("CONFIG", string(c)))
+
+	return cmd
+}
 
-	p := &Panel{
-		Cmd:        cmd,
-		name:       name,
-		config:     config,
-		socketPath: socketPath,
+// Reset rebuilds Cmd so that an exited panel can be started again.
+// Env, Dir and standard streams of the previous Cmd are carried over.
+func (p *Panel) Reset() error {
+	if p.exited != nil {
+		select {
+		case <-p.exited:
+		default:
+			return fmt.Errorf("panel still running")
+		}
 	}
 
-	shmStream, err := shmstream.New(shmstream.Config{Bidirectional: true})
-	if err == nil {
-		p.shmStream = shmStream
-		cmd.Env = append(cmd.Env, GetEnvPair("SHM_PATH", shmStream.Path()))
-		reader, _ := shmStream.NewReader()
-		writer, _ := shmStream.NewWriter()
-		p.shmIo = &struct {
-			io.Reader
-			io.Writer
-		}{reader, writer}
+	old := p.Cmd
+	cmd := p.newCmd()
+	cmd.Env = old.Env
+	cmd.Dir = old.Dir
+	cmd.Stdin = old.Stdin
+	cmd.Stdout = old.Stdout
+	cmd.Stderr = p.stderr
+
+	p.Cmd = cmd
+	p.started = false
+	p.exited = nil
+	p.waitErr = nil
+	return nil
+}
+
+// State queries the panel's kitty for its current state.
+func (p *Panel) State() (PanelState, error) {
+	return p.Kitty().State()
+}
+
+func (p *Panel) StateContext(ctx context.Context) (PanelState, error) {
+	return p.Kitty().StateContext(ctx)
+}
+
+// SocketWait is how long the client returned by Panel.Kitty waits for kitty
+// to create its socket before a command fails.
+var SocketWait = 5 * time.Second
+
+// Kitty returns a client for the panel's remote-control socket, so the host
+// can control the panel directly. It connects on the first command, waiting
+// up to SocketWait for a starting panel to create the socket. The client is
+// shared by all callers and survives restarts of the panel.
+func (p *Panel) Kitty() *Kitty {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.kitty == nil {
+		p.kitty = NewKitty(p.socketPath)
+		p.kitty.dialWait = SocketWait
+		p.kitty.peer = p.peer
+		p.kitty.setConfig(p.config)
 	}
-	return p
+	return p.kitty
+}
+
+// peer returns the handshake of the current run, if it completed.
+func (p *Panel) peer() (Hello, bool) {
+	stream := p.currentStream()
+	if stream == nil {
+		return Hello{}, false
+	}
+	if done, err := stream.finished(); !done || err != nil {
+		return Hello{}, false
+	}
+	hello, err := stream.result(context.Background())
+	return hello, err == nil
+}
+
+// currentStream returns the shared memory stream, or nil once cleanup
+// dropped it.
+func (p *Panel) currentStream() *hostStream {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.stream
+}
+
+func (p *Panel) Name() string {
+	return p.name
+}
+
+func (p *Panel) watcherPath() string {
+	return p.socketPath + "-watcher.py"
+}
+
+func (p *Panel) rcConfigPath() string {
+	return p.socketPath + "-rc.conf"
+}
+
+// rcConfig is the kitty config holding Config.RemoteControlPassword.
+func (p *Panel) rcConfig() ([]byte, error) {
+	password := p.config.RemoteControlPassword
+	if strings.ContainsAny(password, "\r\n") {
+		return nil, fmt.Errorf("remote control password must not contain line breaks")
+	}
+
+	line := "remote_control_password " + shellQuote(password)
+	for _, action := range p.config.RemoteControlActions {
+		line += " " + shellQuote(action)
+	}
+	return []byte("# generated by katnip\n" + line + "\n"), nil
+}
+
+// writeFile creates a private file for kitty, removed again by cleanup.
+func (p *Panel) writeFile(path string, data []byte) error {
+	os.Remove(path)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.files = append(p.files, path)
+	p.mu.Unlock()
+
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
 }
 
 func (p *Panel) cleanup() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, path := range p.files {
+		os.Remove(path)
+	}
+	p.files = nil
+
 	if p.shmStream != nil {
-		p.shmStream.Close()
+		if p.stream == nil || p.stream.close(p.shmStream.Path()) {
+			p.shmStream.Close()
+		} else {
+			// a writer is still blocked on the mapping, only drop the fd
+			unix.Close(int(p.shmStream.Fd()))
+		}
 		p.shmStream = nil
 		p.shmIo = nil
+		p.stream = nil
+		p.channel = nil
+		p.rpc = nil
 	}
 }
 
 func (p *Panel) Run() error {
-	if p.started {
-		return fmt.Errorf("panel already started")
+	if err := p.Start(); err != nil {
+		return err
 	}
-	p.started = true
-	return p.Cmd.Run()
+	return p.Wait()
 }
 
 func (p *Panel) Start() error {
 	if p.started {
 		return fmt.Errorf("panel already started")
 	}
+	if p.argsErr != nil {
+		return p.argsErr
+	}
+	if p.config.Watcher {
+		if err := p.writeFile(p.watcherPath(), []byte(watcherScript)); err != nil {
+			return fmt.Errorf("failed to write watcher script: %w", err)
+		}
+	}
+	if p.config.RemoteControlPassword != "" {
+		conf, err := p.rcConfig()
+		if err != nil {
+			return err
+		}
+		if err := p.writeFile(p.rcConfigPath(), conf); err != nil {
+			return fmt.Errorf("failed to write remote control config: %w", err)
+		}
+	}
 	p.started = true
 
-	return p.Cmd.Start()
+	// a stale marker from a previous run would signal readiness too early
+	os.Remove(p.readyPath())
+
+	p.stderr = p.Cmd.Stderr
+	p.stderrTail = &tailBuffer{max: stderrTailSize}
+	if p.stderr == nil {
+		p.Cmd.Stderr = p.stderrTail
+	} else {
+		p.Cmd.Stderr = io.MultiWriter(p.stderr, p.stderrTail)
+	}
+
+	stream := p.currentStream()
+	var gen uint64
+	if stream != nil {
+		gen = stream.begin(welcome{Args: p.args, Password: p.config.RemoteControlPassword})
+	} else {
+		// Reset carries the environment over, replace the previous run's
+		p.Cmd.Env = setEnvPair(p.Cmd.Env, "ARGS", string(p.args))
+		p.Cmd.Env = setEnvPair(p.Cmd.Env, "PASSWORD", p.config.RemoteControlPassword)
+	}
+
+	if err := p.Cmd.Start(); err != nil {
+		if stream != nil {
+			stream.fail(gen, err)
+		}
+		return err
+	}
+
+	// a panel that never says hello must not block the stream forever
+	var timeout *time.Timer
+	if stream != nil && HandshakeTimeout > 0 {
+		timeout = time.AfterFunc(HandshakeTimeout, func() {
+			stream.fail(gen, errHandshakeTimeout)
+		})
+	}
+
+	exited := make(chan struct{})
+	p.exited = exited
+	go func() {
+		p.waitErr = p.Cmd.Wait()
+		if stream != nil {
+			if timeout != nil {
+				timeout.Stop()
+			}
+			stream.fail(gen, errPanelExited)
+		}
+		close(exited)
+	}()
+	return nil
+}
+
+// SetArgs replaces Config.Args for every following start. v is encoded as
+// JSON and handed to the panel handler, see Instance.Args.
+func (p *Panel) SetArgs(v any) error {
+	args, err := encodeArgs(v)
+	if err != nil {
+		return err
+	}
+	p.args, p.argsErr = args, nil
+	return nil
+}
+
+func encodeArgs(v any) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	args, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode args: %w", err)
+	}
+	return args, nil
+}
+
+// Hello waits for the handshake of the current run and returns what the
+// panel process announced. It fails if the panel exited first or speaks
+// another protocol version (*VersionError).
+func (p *Panel) Hello(ctx context.Context) (Hello, error) {
+	stream := p.currentStream()
+	if stream == nil {
+		return Hello{}, fmt.Errorf("no shared memory available")
+	}
+	return stream.result(ctx)
 }
 
 func (p *Panel) Wait() error {
-	return p.Cmd.Wait()
+	if p.exited == nil {
+		return fmt.Errorf("panel not started")
+	}
+	<-p.exited
+	return p.waitErr
 }
 
 func (p *Panel) Stop() error {
@@ -284,6 +661,39 @@ func (p *Panel) ReadWriter() io.ReadWriter {
 	return p.shmIo
 }
 
+// Channel returns a message channel over the shared memory stream using
+// JSONCodec, or nil if no shared memory is available.
+// Use NewChannel with ReadWriter for other codecs.
+func (p *Panel) Channel() *Channel {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.shmIo == nil {
+		return nil
+	}
+	if p.channel == nil {
+		p.channel = NewChannel(p.shmIo, nil)
+	}
+	return p.channel
+}
+
+// RPC returns the RPC endpoint on the panel's Channel, or nil if no shared
+// memory is available.
+func (p *Panel) RPC() *RPC {
+	ch := p.Channel()
+	if ch == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.rpc == nil {
+		p.rpc = NewRPC(ch)
+	}
+	return p.rpc
+}
+
 // ReadOutput reads all available output from the panel
 // Returns empty slice if no shared memory reader is available
 func (p *Panel) ReadOutput() ([]byte, error) {
@@ -304,7 +714,8 @@ func (p *Panel) ReadOutput() ([]byte, error) {
 func NewPanelContext(ctx context.Context, name string, config Config) *Panel {
 	p := NewPanel(name, config)
 
-	cmd := exec.CommandContext(ctx, p.Cmd.Args[0], p.Cmd.Args[1:]...)
+	p.ctx = ctx
+	cmd := p.newCmd()
 	cmd.Env = p.Cmd.Env
 	p.Cmd = cmd
 
@@ -0,0 +1,152 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StartTimeout bounds StartAndWait when ctx has no deadline.
+var StartTimeout = 10 * time.Second
+
+const stderrTailSize = 8 << 10
+
+// StartError is returned by StartAndWait when kitty exits before the panel
+// is ready.
+type StartError struct {
+	Err error
+	// last few KiB kitty wrote to stderr
+	Stderr string
+}
+
+func (e *StartError) Error() string {
+	msg := "panel exited during startup"
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		msg += "\n" + stderr
+	}
+	return msg
+}
+
+func (e *StartError) Unwrap() error {
+	return e.Err
+}
+
+// StartAndWait starts the panel and waits until it is ready, see WaitReady.
+func (p *Panel) StartAndWait(ctx context.Context) error {
+	if err := p.Start(); err != nil {
+		return err
+	}
+	return p.WaitReady(ctx)
+}
+
+// WaitReady blocks until kitty accepts remote-control commands on the
+// panel's socket and the panel handler has started, or ctx is done. If
+// kitty exits first, a *StartError carrying its stderr is returned; a
+// failed handshake is returned as soon as it happens. The panel is left
+// running when ctx is done.
+func (p *Panel) WaitReady(ctx context.Context) error {
+	if p.exited == nil {
+		return fmt.Errorf("panel not started")
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, StartTimeout)
+		defer cancel()
+	}
+
+	ticker := time.NewTicker(socketPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if stream := p.currentStream(); stream != nil {
+			done, err := stream.finished()
+			if err != nil && !errors.Is(err, errPanelExited) {
+				return fmt.Errorf("panel %q: %w", p.name, err)
+			}
+			if done && err == nil && p.ready() {
+				return nil
+			}
+		} else if p.ready() {
+			return nil
+		}
+
+		select {
+		case <-p.exited:
+			return &StartError{Err: p.waitErr, Stderr: p.stderrTail.String()}
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for panel %q: %w", p.name, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// readyPath is created by the panel process right before the handler runs.
+func (p *Panel) readyPath() string {
+	return p.socketPath + "-ready"
+}
+
+func (p *Panel) ready() bool {
+	if _, err := os.Stat(p.readyPath()); err != nil {
+		return false
+	}
+
+	conn, err := net.Dial("unix", p.socketPath)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	mu  sync.Mutex
+	max int
+	buf []byte
+}
+
+func (t *tailBuffer) Write(b []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.buf = append(t.buf, b...)
+	if over := len(t.buf) - t.max; over > 0 {
+		t.buf = append(t.buf[:0], t.buf[over:]...)
+	}
+	return len(b), nil
+}
+
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return string(t.buf)
+}
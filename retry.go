@@ -0,0 +1,224 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// RetryPolicy controls how a Kitty retries commands that failed because of
+// the connection, e.g. when kitty was restarted under the same socket.
+// Commands are retried on a fresh connection. Any command is retried when
+// the socket could not be dialed; once a command may have reached kitty it
+// is only retried if it is idempotent, so send-text or launch never run
+// twice.
+type RetryPolicy struct {
+	// total number of tries, values below 2 disable retrying
+	Attempts int
+	// delay before the first retry, doubled for every further one
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is used by clients created with NewKitty.
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:   3,
+	Backoff:    100 * time.Millisecond,
+	MaxBackoff: 2 * time.Second,
+}
+
+// SetRetry replaces the retry policy of k.
+func (k *Kitty) SetRetry(policy RetryPolicy) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.retry = policy
+}
+
+// OnReconnect registers fn to run after k had to establish a new
+// connection because the previous one was lost. Use it to re-apply state a
+// restarted kitty forgot, like opacity or font size. fn runs on the
+// goroutine of the command that reconnected, after that command finished,
+// and may use k.
+func (k *Kitty) OnReconnect(fn func()) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.reconnectHooks = append(k.reconnectHooks, fn)
+}
+
+// connError is a failure of the connection rather than of the command.
+type connError struct {
+	err error
+	// the command may have reached kitty
+	sent bool
+}
+
+func (e *connError) Error() string {
+	return e.err.Error()
+}
+
+func (e *connError) Unwrap() error {
+	return e.err
+}
+
+// commands that may safely run twice
+var idempotentCmds = map[string]bool{
+	"ls":                     true,
+	"get-text":               true,
+	"get-colors":             true,
+	"set-colors":             true,
+	"set-window-title":       true,
+	"set-tab-title":          true,
+	"set-font-size":          true,
+	"set-background-opacity": true,
+	"resize-os-window":       true,
+	"focus-window":           true,
+	"focus-tab":              true,
+}
+
+func (r SetFontSizeRequest) idempotent() bool {
+	return r.IncrementOp == ""
+}
+
+func (r SetBackgroundOpacityRequest) idempotent() bool {
+	return !r.Toggle
+}
+
+func (r ResizeOSWindowRequest) idempotent() bool {
+	switch r.Action {
+	case "toggle-visibility":
+		return false
+	case "os-panel", "show", "hide":
+		return true
+	}
+	// incremental resizes are relative
+	return !r.Incremental
+}
+
+func isIdempotent(cmd string, payload any) bool {
+	if !idempotentCmds[cmd] {
+		return false
+	}
+	if p, ok := payload.(interface{ idempotent() bool }); ok {
+		return p.idempotent()
+	}
+	return true
+}
+
+// roundTrip sends a command, retrying connection failures according to the
+// retry policy, and returns the raw response.
+func (k *Kitty) roundTrip(ctx context.Context, cmd string, payload any) ([]byte, error) {
+	return k.exchange(ctx, cmd, isIdempotent(cmd, payload), func() ([]byte, error) {
+		return k.command(cmd, payload)
+	})
+}
+
+// exchange runs fn on a connected socket, retrying connection failures
+// according to the retry policy. name identifies the exchange in errors.
+func (k *Kitty) exchange(ctx context.Context, name string, idempotent bool, fn func() ([]byte, error)) ([]byte, error) {
+	k.mu.Lock()
+	policy := k.retry
+	k.mu.Unlock()
+
+	delay := policy.Backoff
+	for attempt := 1; ; attempt++ {
+		resp, err := k.try(ctx, name, fn)
+
+		var ce *connError
+		if !errors.As(err, &ce) || attempt >= policy.Attempts || (ce.sent && !idempotent) {
+			return resp, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("kitty %s: %w", name, ctx.Err())
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if policy.MaxBackoff > 0 && delay > policy.MaxBackoff {
+			delay = policy.MaxBackoff
+		}
+	}
+}
+
+func (k *Kitty) try(ctx context.Context, name string, fn func() ([]byte, error)) ([]byte, error) {
+	k.mu.Lock()
+	resp, err := k.tryLocked(ctx, name, fn)
+	var hooks []func()
+	if k.reconnected && err == nil {
+		k.reconnected = false
+		hooks = k.reconnectHooks
+	}
+	k.mu.Unlock()
+
+	for _, fn := range hooks {
+		fn()
+	}
+	return resp, err
+}
+
+// tryLocked runs one command with the deadline of ctx applied to the
+// socket. Cancelling ctx expires the deadline to interrupt a blocked read;
+// a connection interrupted that way is dropped since a response may still
+// be on its way.
+func (k *Kitty) tryLocked(ctx context.Context, name string, fn func() ([]byte, error)) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("kitty %s: %w", name, err)
+	}
+	if err := k.ensureConnected(ctx); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("kitty %s: %w", name, err)
+		}
+		return nil, err
+	}
+
+	conn := k.conn
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	interrupted := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+		close(interrupted)
+	})
+
+	resp, err := fn()
+
+	if !stop() {
+		<-interrupted
+	}
+	if err != nil && (ctx.Err() != nil || errors.Is(err, os.ErrDeadlineExceeded)) {
+		k.reset()
+		cause := ctx.Err()
+		if cause == nil {
+			cause = context.DeadlineExceeded
+		}
+		return nil, fmt.Errorf("kitty %s: %w", name, cause)
+	}
+	if k.conn == conn {
+		conn.SetDeadline(time.Time{})
+	}
+	return resp, err
+}
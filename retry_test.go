@@ -0,0 +1,117 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nekorg/katnip"
+	"github.com/nekorg/katnip/katniptest"
+)
+
+// newKitty returns a client of a fake kitty that retries without delay.
+func newKitty(t *testing.T) (*katnip.Kitty, *katniptest.Server) {
+	t.Helper()
+
+	s, err := katniptest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	k := s.Kitty()
+	t.Cleanup(func() { k.Close() })
+	k.SetRetry(katnip.RetryPolicy{Attempts: 3, Backoff: time.Millisecond})
+	return k, s
+}
+
+func count(cmds []katniptest.Command, name string) int {
+	n := 0
+	for _, cmd := range cmds {
+		if cmd.Name == name {
+			n++
+		}
+	}
+	return n
+}
+
+func TestRetryIdempotent(t *testing.T) {
+	k, s := newKitty(t)
+	s.Respond("ls", katniptest.Disconnect(), katniptest.Disconnect(), katniptest.OK([]int{}))
+
+	if _, err := k.Ls(katnip.LsRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(s.Commands(), "ls"); n != 3 {
+		t.Errorf("ls sent %d times, want 3", n)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	k, s := newKitty(t)
+	s.Respond("ls", katniptest.Disconnect(), katniptest.Disconnect(), katniptest.Disconnect())
+
+	if _, err := k.Ls(katnip.LsRequest{}); err == nil {
+		t.Fatal("no error after every attempt failed")
+	}
+	if n := count(s.Commands(), "ls"); n != 3 {
+		t.Errorf("ls sent %d times, want 3", n)
+	}
+}
+
+func TestNoRetryAfterSend(t *testing.T) {
+	k, s := newKitty(t)
+	s.Respond("send-text", katniptest.Disconnect())
+
+	if err := k.SendText(katnip.SendTextRequest{Text: "x"}); err == nil {
+		t.Fatal("no error for a dropped send-text")
+	}
+	// the next command redials
+	if _, err := k.Ls(katnip.LsRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(s.Commands(), "send-text"); n != 1 {
+		t.Errorf("send-text sent %d times, want 1", n)
+	}
+}
+
+func TestOnReconnect(t *testing.T) {
+	k, s := newKitty(t)
+	reconnects := 0
+	k.OnReconnect(func() { reconnects++ })
+
+	if _, err := k.Ls(katnip.LsRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if reconnects != 0 {
+		t.Fatalf("hook ran %d times on the first connection", reconnects)
+	}
+
+	s.DropConnections()
+	if _, err := k.Ls(katnip.LsRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.Ls(katnip.LsRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if reconnects != 1 {
+		t.Errorf("hook ran %d times, want 1", reconnects)
+	}
+}
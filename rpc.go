@@ -0,0 +1,254 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message types used by RPC. The request id and method are carried in the
+// type so that bodies are encoded once, with whatever codec the channel uses.
+//
+//	rpc:call:<id>:<method>  body: args
+//	rpc:result:<id>         body: result
+//	rpc:error:<id>          body: error string
+//	rpc:cancel:<id>
+const (
+	rpcPrefix       = "rpc:"
+	rpcCallPrefix   = "rpc:call:"
+	rpcResultPrefix = "rpc:result:"
+	rpcErrorPrefix  = "rpc:error:"
+	rpcCancelPrefix = "rpc:cancel:"
+)
+
+// DefaultRPCTimeout applies to calls whose context has no deadline.
+const DefaultRPCTimeout = 30 * time.Second
+
+var ErrRPCClosed = errors.New("rpc channel closed")
+
+// RemoteError is an error returned by a method on the other side.
+type RemoteError struct {
+	Method  string
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("remote %s: %s", e.Method, e.Message)
+}
+
+// RPCFunc handles a call. args holds the encoded arguments, decode them
+// with args.Decode. ctx is cancelled when the caller gives up.
+type RPCFunc func(ctx context.Context, args Message) (any, error)
+
+type rpcResult struct {
+	msg Message
+	err error
+}
+
+type rpcPending struct {
+	method string
+	result chan rpcResult
+}
+
+// RPC provides bidirectional request/response calls over a Channel. Both
+// the host and the panel create one and register the methods they serve.
+type RPC struct {
+	ch *Channel
+
+	// Timeout applies to calls whose context has no deadline.
+	// default: DefaultRPCTimeout
+	Timeout time.Duration
+
+	mu       sync.Mutex
+	methods  map[string]RPCFunc
+	pending  map[uint64]*rpcPending
+	inflight map[uint64]context.CancelFunc
+	nextID   uint64
+}
+
+// NewRPC routes rpc messages of ch to the returned RPC. Other messages
+// remain available from ch.Messages.
+func NewRPC(ch *Channel) *RPC {
+	r := &RPC{
+		ch:       ch,
+		Timeout:  DefaultRPCTimeout,
+		methods:  map[string]RPCFunc{},
+		pending:  map[uint64]*rpcPending{},
+		inflight: map[uint64]context.CancelFunc{},
+	}
+	ch.Route(rpcPrefix, r.handle)
+	return r
+}
+
+// Register makes fn callable by the other side as method.
+func (r *RPC) Register(method string, fn RPCFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.methods[method] = fn
+}
+
+// Call invokes method on the other side and decodes its result into
+// result, which may be nil.
+func (r *RPC) Call(ctx context.Context, method string, args, result any) error {
+	if strings.Contains(method, ":") {
+		return fmt.Errorf("invalid method name %q", method)
+	}
+
+	if _, ok := ctx.Deadline(); !ok && r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+
+	r.mu.Lock()
+	r.nextID++
+	id := r.nextID
+	pending := &rpcPending{method: method, result: make(chan rpcResult, 1)}
+	r.pending[id] = pending
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		delete(r.pending, id)
+		r.mu.Unlock()
+	}()
+
+	if err := r.ch.SendContext(ctx, rpcCallPrefix+strconv.FormatUint(id, 10)+":"+method, args); err != nil {
+		return err
+	}
+
+	select {
+	case res := <-pending.result:
+		if res.err != nil {
+			return res.err
+		}
+		if result == nil {
+			return nil
+		}
+		if err := res.msg.Decode(result); err != nil {
+			return fmt.Errorf("failed to decode %s result: %w", method, err)
+		}
+		return nil
+	case <-ctx.Done():
+		// the other side may not be reading, do not wait for it
+		go r.ch.Send(rpcCancelPrefix+strconv.FormatUint(id, 10), nil)
+		return ctx.Err()
+	case <-r.ch.Done():
+		return ErrRPCClosed
+	}
+}
+
+// handle runs on the channel's receive loop.
+func (r *RPC) handle(msg Message) {
+	switch {
+	case strings.HasPrefix(msg.Type, rpcCallPrefix):
+		idStr, method, ok := strings.Cut(strings.TrimPrefix(msg.Type, rpcCallPrefix), ":")
+		id, err := strconv.ParseUint(idStr, 10, 64)
+		if !ok || err != nil {
+			return
+		}
+		r.serve(id, method, msg)
+
+	case strings.HasPrefix(msg.Type, rpcResultPrefix):
+		r.resolve(strings.TrimPrefix(msg.Type, rpcResultPrefix), func(p *rpcPending) rpcResult {
+			return rpcResult{msg: msg}
+		})
+
+	case strings.HasPrefix(msg.Type, rpcErrorPrefix):
+		r.resolve(strings.TrimPrefix(msg.Type, rpcErrorPrefix), func(p *rpcPending) rpcResult {
+			var text string
+			if err := msg.Decode(&text); err != nil {
+				text = string(msg.Body)
+			}
+			return rpcResult{err: &RemoteError{Method: p.method, Message: text}}
+		})
+
+	case strings.HasPrefix(msg.Type, rpcCancelPrefix):
+		id, err := strconv.ParseUint(strings.TrimPrefix(msg.Type, rpcCancelPrefix), 10, 64)
+		if err != nil {
+			return
+		}
+		r.mu.Lock()
+		cancel := r.inflight[id]
+		r.mu.Unlock()
+		if cancel != nil {
+			cancel()
+		}
+	}
+}
+
+func (r *RPC) resolve(idStr string, result func(*rpcPending) rpcResult) {
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		return
+	}
+
+	// the first reply claims the call; duplicate replies and late replies
+	// to calls that already timed out are dropped
+	r.mu.Lock()
+	p := r.pending[id]
+	delete(r.pending, id)
+	r.mu.Unlock()
+
+	if p != nil {
+		p.result <- result(p)
+	}
+}
+
+func (r *RPC) serve(id uint64, method string, args Message) {
+	idStr := strconv.FormatUint(id, 10)
+
+	r.mu.Lock()
+	fn := r.methods[method]
+	if fn == nil {
+		r.mu.Unlock()
+		go r.ch.Send(rpcErrorPrefix+idStr, "method not found: "+method)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.inflight[id] = cancel
+	r.mu.Unlock()
+
+	args.Type = method
+	go func() {
+		defer func() {
+			r.mu.Lock()
+			delete(r.inflight, id)
+			r.mu.Unlock()
+			cancel()
+		}()
+
+		result, err := fn(ctx, args)
+		if err != nil {
+			r.ch.Send(rpcErrorPrefix+idStr, err.Error())
+			return
+		}
+		if err := r.ch.Send(rpcResultPrefix+idStr, result); err != nil {
+			r.ch.Send(rpcErrorPrefix+idStr, err.Error())
+		}
+	}()
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// channelPair connects two Channels through in-memory pipes.
+func channelPair(t *testing.T) (*Channel, *Channel) {
+	t.Helper()
+
+	ar, bw := io.Pipe()
+	br, aw := io.Pipe()
+	t.Cleanup(func() {
+		aw.Close()
+		bw.Close()
+	})
+	a := NewChannel(struct {
+		io.Reader
+		io.Writer
+	}{ar, aw}, nil)
+	b := NewChannel(struct {
+		io.Reader
+		io.Writer
+	}{br, bw}, nil)
+	return a, b
+}
+
+func TestCall(t *testing.T) {
+	a, b := channelPair(t)
+	client, server := NewRPC(a), NewRPC(b)
+	server.Register("add", func(ctx context.Context, args Message) (any, error) {
+		var xs []int
+		if err := args.Decode(&xs); err != nil {
+			return nil, err
+		}
+		return xs[0] + xs[1], nil
+	})
+	server.Register("fail", func(ctx context.Context, args Message) (any, error) {
+		return nil, errors.New("no battery")
+	})
+
+	var sum int
+	if err := client.Call(t.Context(), "add", []int{2, 3}, &sum); err != nil || sum != 5 {
+		t.Errorf("add = %d, %v", sum, err)
+	}
+
+	var remote *RemoteError
+	err := client.Call(t.Context(), "fail", nil, nil)
+	if !errors.As(err, &remote) || remote.Method != "fail" || remote.Message != "no battery" {
+		t.Errorf("fail: error = %v", err)
+	}
+	err = client.Call(t.Context(), "missing", nil, nil)
+	if !errors.As(err, &remote) || !strings.Contains(remote.Message, "method not found") {
+		t.Errorf("missing: error = %v", err)
+	}
+	if err := client.Call(t.Context(), "a:b", nil, nil); err == nil {
+		t.Error("method with a colon: no error")
+	}
+}
+
+func TestCallCancel(t *testing.T) {
+	a, b := channelPair(t)
+	client, server := NewRPC(a), NewRPC(b)
+	cancelled := make(chan struct{})
+	server.Register("wait", func(ctx context.Context, args Message) (any, error) {
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	if err := client.Call(ctx, "wait", nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want deadline exceeded", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler not cancelled")
+	}
+}
+
+func TestCallDuplicateReply(t *testing.T) {
+	a, b := channelPair(t)
+	client := NewRPC(a)
+
+	// answer every call twice, the second time with a wrong result
+	go func() {
+		for msg := range b.Messages() {
+			id, _, _ := strings.Cut(strings.TrimPrefix(msg.Type, rpcCallPrefix), ":")
+			var x int
+			msg.Decode(&x)
+			b.Send(rpcResultPrefix+id, x)
+			b.Send(rpcResultPrefix+id, -1)
+		}
+	}()
+
+	for i := 1; i <= 3; i++ {
+		var got int
+		if err := client.Call(t.Context(), "echo", i, &got); err != nil || got != i {
+			t.Errorf("call %d = %d, %v", i, got, err)
+		}
+	}
+}
+
+func TestCallBeforeHandshake(t *testing.T) {
+	host, _ := streamPair(t)
+	host.begin(welcome{})
+	r := NewRPC(NewChannel(host, nil))
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := r.Call(ctx, "refresh", nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want deadline exceeded", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Call returned after %v", d)
+	}
+}
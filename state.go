@@ -0,0 +1,109 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// PanelState is a snapshot of a running panel.
+type PanelState struct {
+	OSWindowID int
+	WindowID   int
+
+	Columns, Lines int
+	// size of the window in pixels, only known from within the panel
+	// process, zero otherwise
+	Width, Height int
+
+	Focused bool
+	Opacity float64
+
+	// tracked from SetFontSize calls made through the Kitty client the
+	// state is taken from, kitty does not report it. Zero until set.
+	FontSize float64
+
+	// from the panel's Config, zero if unknown
+	Edge  Edge
+	Layer Layer
+}
+
+// State queries kitty for the current state of the panel. The panel's
+// window is the one set with SetWindowID, inside a panel its own, for
+// Panel.Kitty the one announced in the handshake.
+func (k *Kitty) State() (PanelState, error) {
+	return k.StateContext(context.Background())
+}
+
+func (k *Kitty) StateContext(ctx context.Context) (PanelState, error) {
+	selfID := k.selfWindowID()
+	if selfID == 0 {
+		return PanelState{}, fmt.Errorf("panel window not known")
+	}
+
+	windows, err := k.LsContext(ctx, LsRequest{})
+	if err != nil {
+		return PanelState{}, err
+	}
+
+	var state PanelState
+	found := false
+	for _, osw := range windows {
+		for _, tab := range osw.Tabs {
+			for _, w := range tab.Windows {
+				if w.ID != selfID {
+					continue
+				}
+				state = PanelState{
+					OSWindowID: osw.ID,
+					WindowID:   w.ID,
+					Columns:    w.Columns,
+					Lines:      w.Lines,
+					Focused:    osw.IsFocused && tab.IsActive && w.IsActive,
+					Opacity:    osw.BackgroundOpacity,
+				}
+				found = true
+			}
+		}
+	}
+	if !found {
+		return PanelState{}, fmt.Errorf("panel window not found")
+	}
+
+	if k.inPanel {
+		if ws, err := unix.IoctlGetWinsize(int(os.Stdout.Fd()), unix.TIOCGWINSZ); err == nil {
+			state.Width, state.Height = int(ws.Xpixel), int(ws.Ypixel)
+		}
+	}
+
+	k.mu.Lock()
+	state.FontSize = k.fontSize
+	k.mu.Unlock()
+
+	if k.config != nil {
+		state.Edge = k.config.Edge
+		state.Layer = k.config.Layer
+	}
+	return state, nil
+}
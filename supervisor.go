@@ -0,0 +1,333 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+//go:generate stringer -type=RestartPolicy,EventKind -linecomment -output supervisor_string.go
+package katnip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+type RestartPolicy int
+
+const (
+	RestartNever     RestartPolicy = iota + 1 // never
+	RestartOnFailure                          // on-failure
+	RestartAlways                             // always
+)
+
+type EventKind int
+
+const (
+	EventStarted    EventKind = iota + 1 // started
+	EventExited                          // exited
+	EventRestarting                      // restarting
+	EventGaveUp                          // gave-up
+)
+
+// Event reports a lifecycle change of a supervised panel.
+type Event struct {
+	Kind  EventKind
+	Panel *Panel
+
+	// 1 for the first run, incremented on every restart
+	Attempt int
+
+	// set for EventExited, -1 if the panel was killed by a signal
+	// or could not be started
+	ExitCode int
+	Err      error
+
+	// set for EventRestarting, time until the next attempt
+	Delay time.Duration
+}
+
+type SupervisorConfig struct {
+	// default: RestartOnFailure
+	Restart RestartPolicy
+
+	// delay before the first restart, doubled after every failed attempt
+	// up to MaxBackoff. default: 500ms, 30s
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// a run lasting at least ResetAfter resets the backoff. default: 10s
+	ResetAfter time.Duration
+
+	// give up after MaxRestarts restarts within RestartWindow.
+	// default: 5, 1m
+	MaxRestarts   int
+	RestartWindow time.Duration
+}
+
+func (c *SupervisorConfig) setDefaults() {
+	if c.Restart == 0 {
+		c.Restart = RestartOnFailure
+	}
+	if c.InitialBackoff <= 0 {
+		c.InitialBackoff = 500 * time.Millisecond
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = 30 * time.Second
+	}
+	if c.ResetAfter <= 0 {
+		c.ResetAfter = 10 * time.Second
+	}
+	if c.MaxRestarts <= 0 {
+		c.MaxRestarts = 5
+	}
+	if c.RestartWindow <= 0 {
+		c.RestartWindow = time.Minute
+	}
+}
+
+// Supervisor runs panels and restarts them according to its RestartPolicy.
+type Supervisor struct {
+	config SupervisorConfig
+	events chan Event
+
+	mu      sync.Mutex
+	panels  []*Panel
+	runs    map[*Panel]*run
+	ctx     context.Context
+	cancel  context.CancelFunc
+	started bool
+	wg      sync.WaitGroup
+}
+
+func NewSupervisor(config SupervisorConfig, panels ...*Panel) *Supervisor {
+	config.setDefaults()
+	return &Supervisor{
+		config: config,
+		events: make(chan Event, 64),
+		panels: panels,
+		runs:   map[*Panel]*run{},
+	}
+}
+
+// Events returns the channel on which lifecycle events are delivered.
+// Events are dropped when the channel is full.
+func (s *Supervisor) Events() <-chan Event {
+	return s.events
+}
+
+// Add supervises p. If the supervisor is already started, p is started
+// immediately.
+func (s *Supervisor) Add(p *Panel) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.panels = append(s.panels, p)
+	if s.started {
+		s.launch(p)
+	}
+}
+
+// Remove stops supervising p, stops it if running the way Shutdown does
+// and waits for it to exit. p keeps its resources and can be added again.
+func (s *Supervisor) Remove(p *Panel) {
+	s.mu.Lock()
+	for i, sp := range s.panels {
+		if sp == p {
+			s.panels = append(s.panels[:i], s.panels[i+1:]...)
+			break
+		}
+	}
+	r := s.runs[p]
+	delete(s.runs, p)
+	s.mu.Unlock()
+
+	if r != nil {
+		r.cancel()
+		<-r.done
+	}
+}
+
+// run is the supervision goroutine of a panel.
+type run struct {
+	cancel context.CancelFunc
+	// closed when the goroutine returns
+	done chan struct{}
+}
+
+// launch must be called with s.mu held.
+func (s *Supervisor) launch(p *Panel) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	r := &run{cancel: cancel, done: make(chan struct{})}
+	s.runs[p] = r
+	s.wg.Add(1)
+	go s.supervise(ctx, p, r)
+}
+
+// finish forgets r once its goroutine returns, unless p was removed or
+// added again meanwhile.
+func (s *Supervisor) finish(p *Panel, r *run) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r.cancel()
+	if s.runs[p] == r {
+		delete(s.runs, p)
+	}
+	close(r.done)
+}
+
+// Start starts all panels. Cancelling ctx stops them, same as Stop.
+func (s *Supervisor) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return fmt.Errorf("supervisor already started")
+	}
+	s.started = true
+	s.ctx, s.cancel = context.WithCancel(ctx)
+
+	for _, p := range s.panels {
+		s.launch(p)
+	}
+	return nil
+}
+
+// Wait blocks until every panel has stopped for good.
+func (s *Supervisor) Wait() {
+	s.wg.Wait()
+}
+
+// Stop disables restarts, stops all panels the way Shutdown does and waits
+// for them to exit.
+func (s *Supervisor) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	s.wg.Wait()
+}
+
+func (s *Supervisor) emit(e Event) {
+	select {
+	case s.events <- e:
+	default:
+	}
+}
+
+func (s *Supervisor) shouldRestart(code int, err error) bool {
+	switch s.config.Restart {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return code != 0 || err != nil
+	default:
+		return false
+	}
+}
+
+func (s *Supervisor) supervise(ctx context.Context, p *Panel, r *run) {
+	defer s.wg.Done()
+	defer s.finish(p, r)
+
+	backoff := s.config.InitialBackoff
+	var restarts []time.Time
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 {
+			if err := p.Reset(); err != nil {
+				s.emit(Event{Kind: EventGaveUp, Panel: p, Attempt: attempt, Err: err})
+				return
+			}
+		}
+
+		began := time.Now()
+		code, err := s.runOnce(ctx, p, attempt)
+		s.emit(Event{Kind: EventExited, Panel: p, Attempt: attempt, ExitCode: code, Err: err})
+
+		if ctx.Err() != nil || !s.shouldRestart(code, err) {
+			return
+		}
+
+		now := time.Now()
+		if now.Sub(began) >= s.config.ResetAfter {
+			backoff = s.config.InitialBackoff
+		}
+
+		recent := restarts[:0]
+		for _, t := range restarts {
+			if now.Sub(t) < s.config.RestartWindow {
+				recent = append(recent, t)
+			}
+		}
+		restarts = recent
+		if len(restarts) >= s.config.MaxRestarts {
+			s.emit(Event{
+				Kind:    EventGaveUp,
+				Panel:   p,
+				Attempt: attempt,
+				Err:     fmt.Errorf("restarted %d times within %s", len(restarts), s.config.RestartWindow),
+			})
+			return
+		}
+		restarts = append(restarts, now)
+
+		s.emit(Event{Kind: EventRestarting, Panel: p, Attempt: attempt, Delay: backoff})
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+
+		backoff = min(backoff*2, s.config.MaxBackoff)
+	}
+}
+
+func (s *Supervisor) runOnce(ctx context.Context, p *Panel, attempt int) (int, error) {
+	if err := p.Start(); err != nil {
+		return -1, err
+	}
+	s.emit(Event{Kind: EventStarted, Panel: p, Attempt: attempt})
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			// ctx is already done, give the handler its full grace period
+			p.terminate(context.Background())
+		case <-done:
+		}
+	}()
+
+	err := p.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// a non-zero exit is reported through the exit code
+		err = nil
+	}
+	if p.Cmd.ProcessState == nil {
+		return -1, err
+	}
+	return p.Cmd.ProcessState.ExitCode(), err
+}
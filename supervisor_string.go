@@ -0,0 +1,47 @@
+// Code generated by "stringer -type=RestartPolicy,EventKind -linecomment -output supervisor_string.go"; DO NOT EDIT.
+
+package katnip
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[RestartNever-1]
+	_ = x[RestartOnFailure-2]
+	_ = x[RestartAlways-3]
+}
+
+const _RestartPolicy_name = "neveron-failurealways"
+
+var _RestartPolicy_index = [...]uint8{0, 5, 15, 21}
+
+func (i RestartPolicy) String() string {
+	idx := int(i) - 1
+	if i < 1 || idx >= len(_RestartPolicy_index)-1 {
+		return "RestartPolicy(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _RestartPolicy_name[_RestartPolicy_index[idx]:_RestartPolicy_index[idx+1]]
+}
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[EventStarted-1]
+	_ = x[EventExited-2]
+	_ = x[EventRestarting-3]
+	_ = x[EventGaveUp-4]
+}
+
+const _EventKind_name = "startedexitedrestartinggave-up"
+
+var _EventKind_index = [...]uint8{0, 7, 13, 23, 30}
+
+func (i EventKind) String() string {
+	idx := int(i) - 1
+	if i < 1 || idx >= len(_EventKind_index)-1 {
+		return "EventKind(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _EventKind_name[_EventKind_index[idx]:_EventKind_index[idx+1]]
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2025 Harsh Sharma <harsh@codelif.in>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package katnip
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"git.sr.ht/~rockorager/vaxis"
+)
+
+// TUI is the state shared with TUIPanel callbacks.
+type TUI struct {
+	Vaxis    *vaxis.Vaxis
+	Kitty    *Kitty
+	RW       io.ReadWriter
+	Instance *Instance
+
+	quit chan int
+}
+
+// Redraw schedules a render. Safe to call from any goroutine.
+func (t *TUI) Redraw() {
+	t.Vaxis.PostEvent(vaxis.Redraw{})
+}
+
+// Update runs fn on the event loop, followed by a render. Use it to mutate
+// state read by Render from other goroutines.
+func (t *TUI) Update(fn func()) {
+	t.Vaxis.SyncFunc(fn)
+}
+
+// Quit ends the event loop; code becomes the exit code of the panel.
+func (t *TUI) Quit(code int) {
+	select {
+	case t.quit <- code:
+	default:
+	}
+}
+
+// Size returns the size of the panel window in cells.
+func (t *TUI) Size() (columns, lines int) {
+	return t.Vaxis.Window().Size()
+}
+
+// TUIPanel is a PanelHandler that initialises vaxis in the panel window and
+// runs an event loop around the callbacks. All callbacks run on the event
+// loop goroutine.
+type TUIPanel struct {
+	Options vaxis.Options
+
+	// called once after vaxis is set up, a non-nil error ends the panel
+	Init func(t *TUI) error
+
+	// draws the panel, win is cleared beforehand and covers the whole window
+	Render func(t *TUI, win vaxis.Window)
+
+	// called for every vaxis event (keys, mouse, resize, focus...) before
+	// the panel is redrawn
+	Event func(t *TUI, ev vaxis.Event)
+
+	// redraw periodically when > 0, e.g. for clocks
+	Interval time.Duration
+}
+
+func (p *TUIPanel) Run(k *Kitty, rw io.ReadWriter) int {
+	return p.RunInstance(&Instance{Kitty: k, RW: rw})
+}
+
+func (p *TUIPanel) RunInstance(in *Instance) int {
+	return p.RunContext(context.Background(), in)
+}
+
+// RunContext runs the panel until the event loop ends or ctx is done.
+func (p *TUIPanel) RunContext(ctx context.Context, in *Instance) int {
+	vx, err := vaxis.New(p.Options)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to initialise vaxis: %v\n", err)
+		return 1
+	}
+	defer vx.Close()
+
+	t := &TUI{
+		Vaxis:    vx,
+		Kitty:    in.Kitty,
+		RW:       in.RW,
+		Instance: in,
+		quit:     make(chan int, 1),
+	}
+
+	if p.Init != nil {
+		if err := p.Init(t); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to initialise panel: %v\n", err)
+			return 1
+		}
+	}
+
+	var tick <-chan time.Time
+	if p.Interval > 0 {
+		ticker := time.NewTicker(p.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case ev, ok := <-vx.Events():
+			if !ok {
+				return 0
+			}
+			switch ev := ev.(type) {
+			case vaxis.QuitEvent:
+				return 0
+			case vaxis.SyncFunc:
+				ev()
+			}
+			if p.Event != nil {
+				p.Event(t, ev)
+			}
+		case code := <-t.quit:
+			return code
+		case <-ctx.Done():
+			return 0
+		case <-tick:
+		}
+
+		p.draw(t)
+	}
+}
+
+func (p *TUIPanel) draw(t *TUI) {
+	win := t.Vaxis.Window()
+	win.Clear()
+	if p.Render != nil {
+		p.Render(t, win)
+	}
+	t.Vaxis.Render()
+}
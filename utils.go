@@ -21,7 +21,9 @@ package katnip
 
 import (
 	"errors"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 )
@@ -47,3 +49,39 @@ func (w *NotificationWriter) Write(p []byte) (n int, err error) {
 		return len(p), d
 	}
 }
+
+// shellQuote quotes s for option values kitty splits like a shell would.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// kittyConfigFile returns the kitty.conf kitty loads without --config, or
+// "" if there is none.
+func kittyConfigFile() string {
+	var dirs []string
+	if dir := os.Getenv("KITTY_CONFIG_DIRECTORY"); dir != "" {
+		dirs = append(dirs, dir)
+	} else {
+		if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+			dirs = append(dirs, filepath.Join(dir, "kitty"))
+		}
+		if home, err := os.UserHomeDir(); err == nil {
+			dirs = append(dirs, filepath.Join(home, ".config", "kitty"))
+		}
+		xdgDirs := os.Getenv("XDG_CONFIG_DIRS")
+		if xdgDirs == "" {
+			xdgDirs = "/etc/xdg"
+		}
+		for _, dir := range filepath.SplitList(xdgDirs) {
+			dirs = append(dirs, filepath.Join(dir, "kitty"))
+		}
+	}
+
+	for _, dir := range dirs {
+		path := filepath.Join(dir, "kitty.conf")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}